@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"context"
+	"slices"
+	"strconv"
+)
+
+// Audio codecs for Config.AudioCodec.
+const (
+	AudioCopy = "copy" // pass the input audio through untouched.
+	AudioAuto = "auto" // copy when the container supports the input audio, transcode otherwise.
+	AudioAAC  = "aac"
+	AudioOpus = "opus"
+	AudioMP3  = "mp3"
+)
+
+// audioEncoders maps Config.AudioCodec values to ffmpeg encoders.
+//
+//nolint:gochecknoglobals // this is a constant lookup table.
+var audioEncoders = map[string]string{
+	AudioAAC:  "aac",
+	AudioOpus: "libopus",
+	AudioMP3:  "libmp3lame",
+}
+
+// audioContainers lists the audio codecs (as ffprobe names them) each container can hold.
+//
+//nolint:gochecknoglobals // this is a constant lookup table.
+var audioContainers = map[string][]string{
+	"mov":      {"aac", "mp3", "alac", "ac3", "eac3"},
+	"mp4":      {"aac", "mp3", "alac", "ac3", "eac3", "opus", "flac"},
+	"webm":     {"opus", "vorbis"},
+	"mpegts":   {"aac", "mp3", "ac3", "eac3", "opus"},
+	"matroska": {"aac", "mp3", "alac", "ac3", "eac3", "opus", "vorbis", "flac", "pcm_s16le", "pcm_alaw", "pcm_mulaw"},
+}
+
+// SetAudioCodec sets the audio codec: copy, auto, aac, opus or mp3. Unknown values become copy.
+// Auto runs ffprobe on the input before every capture. That is one more connection to the camera,
+// so pick a codec instead for cameras that allow few sessions.
+// This can also be passed into Get().
+func (e *Encoder) SetAudioCodec(codec string) string {
+	if _, ok := audioEncoders[codec]; !ok && codec != AudioAuto {
+		codec = AudioCopy
+	}
+
+	e.config.AudioCodec = codec
+
+	return e.config.AudioCodec
+}
+
+// sourceAudio returns the codec of the input's audio, but only if AudioCodec auto needs it.
+// Returns an empty string if it cannot be determined; auto transcodes in that case.
+// This opens a second connection to the input for every capture; the result is not cached,
+// because a camera's audio settings can change between captures.
+func (e *Encoder) sourceAudio(ctx context.Context, input string) string {
+	if !e.config.Audio || e.config.AudioCodec != AudioAuto {
+		return ""
+	}
+
+	_, probe, err := e.Probe(ctx, input)
+	if err != nil || probe.Audio() == nil {
+		return ""
+	}
+
+	return probe.Audio().Codec
+}
+
+// audioArgs returns the audio options for a container. Source is the input's audio codec, if known.
+func (e *Encoder) audioArgs(container, source string) []string {
+	if !e.config.Audio {
+		return []string{"-an"}
+	}
+
+	codec := e.config.AudioCodec
+	if codec == AudioAuto {
+		codec = autoAudio(container, source)
+	}
+
+	encoder, ok := audioEncoders[codec]
+	if !ok {
+		return []string{"-c:a", "copy"}
+	}
+
+	arg := []string{"-c:a", encoder}
+
+	if e.config.AudioBitrate > 0 {
+		arg = append(arg, "-b:a", strconv.Itoa(e.config.AudioBitrate)+"k")
+	}
+
+	if e.config.AudioRate > 0 {
+		arg = append(arg, "-ar", strconv.Itoa(e.config.AudioRate))
+	}
+
+	if e.config.AudioChannels > 0 {
+		arg = append(arg, "-ac", strconv.Itoa(e.config.AudioChannels))
+	}
+
+	return arg
+}
+
+// autoAudio returns copy if a container can hold the source audio, or the codec to transcode it to.
+func autoAudio(container, source string) string {
+	if source != "" && slices.Contains(audioContainers[container], source) {
+		return AudioCopy
+	}
+
+	if container == "webm" {
+		return AudioOpus
+	}
+
+	return AudioAAC
+}
@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioArgs(t *testing.T) {
+	t.Parallel()
+
+	asert := assert.New(t)
+
+	encode := Get(&Config{Audio: true})
+	asert.Equal(AudioCopy, encode.Config().AudioCodec)
+	asert.Equal([]string{"-c:a", "copy"}, encode.audioArgs("mov", "pcm_mulaw"))
+	asert.Equal(AudioCopy, encode.SetAudioCodec("wav"))
+
+	encode = Get(&Config{Audio: true, AudioCodec: AudioOpus, AudioBitrate: 64, AudioRate: 48000, AudioChannels: 1})
+	asert.Equal([]string{"-c:a", "libopus", "-b:a", "64k", "-ar", "48000", "-ac", "1"}, encode.audioArgs("webm", ""))
+
+	encode = Get(&Config{Audio: true, AudioCodec: AudioAuto, AudioBitrate: 96})
+	asert.Equal([]string{"-c:a", "copy"}, encode.audioArgs("mov", "aac"))
+	asert.Equal([]string{"-c:a", "aac", "-b:a", "96k"}, encode.audioArgs("mov", "pcm_mulaw"))
+	asert.Equal([]string{"-c:a", "libopus", "-b:a", "96k"}, encode.audioArgs("webm", "aac"))
+	asert.Equal([]string{"-c:a", "aac", "-b:a", "96k"}, encode.audioArgs("mp4", ""), "unknown audio is transcoded")
+
+	encode = Get(&Config{AudioCodec: AudioAAC})
+	asert.Equal([]string{"-an"}, encode.audioArgs("mov", "aac"))
+}
+
+func TestAudioAuto(t *testing.T) {
+	t.Parallel()
+
+	// ffprobe (echo) does not print JSON, so auto has to transcode.
+	encode := Get(&Config{FFMPEG: "echo", FFProbe: "echo", Audio: true, AudioCodec: AudioAuto})
+	cmd, _, err := encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-c:a aac")
+
+	cmd, recorder, err := encode.Record(context.Background(), "INPUT", &Recording{Template: "/tmp/cam-%03d.webm"})
+	require.NoError(t, err)
+	require.NoError(t, recorder.Wait())
+	require.Contains(t, cmd, "-c:a libopus", "webm segments need opus audio")
+}
@@ -0,0 +1,327 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Fragments waiting to be written to a broadcast reader before it is considered too slow.
+const subscriberBuffer = 256
+
+// Broadcaster shares one ffmpeg process per input among any number of readers.
+// Create one with Encoder.Broadcaster().
+type Broadcaster struct {
+	encoder *Encoder
+	ctx     context.Context //nolint:containedctx // every process the broadcaster starts uses it.
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	feeds   map[string]*feed
+	closed  bool
+}
+
+// feed is one running ffmpeg process and the readers attached to it.
+type feed struct {
+	owner    *Broadcaster
+	input    string
+	cmdStr   string
+	stream   io.ReadCloser
+	ready    chan struct{} // closed once the process started, or failed to.
+	startErr error
+	mu       sync.Mutex
+	init     []byte
+	readers  map[*subscriber]struct{}
+	stopped  bool // the last reader left, or the broadcaster closed.
+	done     chan struct{}
+	err      error
+}
+
+// subscriber is one reader attached to a feed.
+type subscriber struct {
+	*io.PipeReader
+
+	feed      *feed
+	writer    *io.PipeWriter
+	chunks    chan []byte
+	stop      chan struct{} // closed if the reader falls behind.
+	closeOnce sync.Once
+}
+
+// Broadcaster returns a fan-out for live streams. Each input gets one ffmpeg process,
+// started by the first reader and stopped when the last reader closes.
+// Time and Size from the config do not apply. Cancel the context or call Close() to stop every process.
+func (e *Encoder) Broadcaster(ctx context.Context) *Broadcaster {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Broadcaster{
+		encoder: e,
+		ctx:     ctx,
+		cancel:  cancel,
+		feeds:   make(map[string]*feed),
+	}
+}
+
+// Subscribe attaches a reader to the stream for input, starting ffmpeg if no reader has it open.
+// The reader produces fragmented MP4: the init segment, then every fragment from the next keyframe on.
+// Title is encoded into the video only by the reader that starts the process.
+// A reader that does not keep up gets ErrSlowReader; the other readers are not affected.
+// Returns command used for diagnostics, io.ReadCloser and error or nil.
+func (b *Broadcaster) Subscribe(input, title string) (string, io.ReadCloser, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	for {
+		current, starting, err := b.feed(input)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if starting {
+			current.start(title)
+		}
+
+		<-current.ready
+
+		if current.startErr != nil {
+			return current.cmdStr, nil, current.startErr
+		}
+
+		if reader := current.subscribe(); reader != nil {
+			return current.cmdStr, reader, nil
+		}
+		// The last reader closed this feed while we joined it; start another.
+	}
+}
+
+// Close stops every ffmpeg process. Open readers get io.EOF.
+func (b *Broadcaster) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	feeds := make([]*feed, 0, len(b.feeds))
+
+	for _, current := range b.feeds {
+		feeds = append(feeds, current)
+	}
+	b.mu.Unlock()
+
+	b.cancel()
+
+	for _, current := range feeds {
+		current.halt()
+	}
+
+	return nil
+}
+
+// feed returns the running feed for an input, or a new one that the caller must start.
+func (b *Broadcaster) feed(input string) (*feed, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, false, ErrClosed
+	}
+
+	if current, ok := b.feeds[input]; ok {
+		return current, false, nil
+	}
+
+	current := &feed{
+		owner:   b,
+		input:   input,
+		ready:   make(chan struct{}),
+		readers: make(map[*subscriber]struct{}),
+		done:    make(chan struct{}),
+	}
+	b.feeds[input] = current
+
+	return current, true, nil
+}
+
+// detach forgets a feed, so the next reader starts a new process.
+func (b *Broadcaster) detach(current *feed) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.feeds[current.input] == current {
+		delete(b.feeds, current.input)
+	}
+}
+
+// start runs ffmpeg for the feed.
+func (f *feed) start(title string) {
+	defer close(f.ready)
+
+	f.cmdStr, f.stream, f.startErr = f.owner.encoder.streamVideo(f.owner.ctx,
+		&videoJob{input: f.input, output: "-", title: title})
+	if f.startErr != nil {
+		f.owner.detach(f)
+		close(f.done)
+
+		return
+	}
+
+	go f.run()
+}
+
+// run reads the stream until it ends.
+func (f *feed) run() {
+	defer close(f.done)
+
+	err := readFragments(f.stream, f.setInit, f.push)
+	closeErr := f.stream.Close()
+
+	f.owner.detach(f)
+
+	f.mu.Lock()
+	stopped := f.stopped
+	f.mu.Unlock()
+
+	if stopped || f.owner.ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		f.err = err
+	} else {
+		f.err = closeErr
+	}
+}
+
+// subscribe adds a reader. Returns nil if the feed is stopping.
+func (f *feed) subscribe() *subscriber {
+	reader, writer := io.Pipe()
+	sub := &subscriber{
+		PipeReader: reader,
+		feed:       f,
+		writer:     writer,
+		chunks:     make(chan []byte, subscriberBuffer),
+		stop:       make(chan struct{}),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return nil
+	}
+
+	f.readers[sub] = struct{}{}
+	go f.write(sub, f.init)
+
+	return sub
+}
+
+func (f *feed) setInit(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init = data
+
+	for sub := range f.readers {
+		f.send(sub, data)
+	}
+}
+
+func (f *feed) push(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.readers {
+		f.send(sub, data)
+	}
+}
+
+// send gives a reader a chunk without blocking. A reader that cannot keep up is stopped.
+// Its pipe is closed too, so a write blocked on a reader that stopped reading returns,
+// and the write goroutine leaves the feed; that stops ffmpeg if it was the last reader.
+// Must be called with the lock held.
+func (f *feed) send(sub *subscriber, data []byte) {
+	select {
+	case sub.chunks <- data:
+	default:
+		delete(f.readers, sub)
+		close(sub.stop)
+		sub.writer.CloseWithError(ErrSlowReader)
+	}
+}
+
+// write copies chunks to a reader until the stream ends or the reader goes away.
+// Init is nil if the reader joined before ffmpeg wrote it; it arrives as a chunk instead.
+func (f *feed) write(sub *subscriber, init []byte) {
+	defer f.leave(sub)
+
+	writer := sub.writer
+
+	if init != nil {
+		if _, err := writer.Write(init); err != nil {
+			return // reader closed.
+		}
+	}
+
+	for {
+		select {
+		case chunk := <-sub.chunks:
+			if _, err := writer.Write(chunk); err != nil {
+				return
+			}
+		case <-sub.stop:
+			writer.CloseWithError(ErrSlowReader)
+
+			return
+		case <-f.done:
+			finishPipe(writer, sub.chunks, f.err)
+
+			return
+		}
+	}
+}
+
+// leave removes a reader, and stops ffmpeg if no readers are left.
+func (f *feed) leave(sub *subscriber) {
+	f.mu.Lock()
+	delete(f.readers, sub)
+	last := len(f.readers) == 0 && !f.stopped
+	f.stopped = f.stopped || last
+	f.mu.Unlock()
+
+	if last {
+		f.shutdown()
+	}
+}
+
+// halt stops ffmpeg, no matter how many readers are attached, and waits for the feed to end.
+func (f *feed) halt() {
+	f.mu.Lock()
+	f.stopped = true
+	f.mu.Unlock()
+
+	f.shutdown()
+}
+
+// shutdown closes the stream and waits for the feed to end. The feed must be marked stopped.
+func (f *feed) shutdown() {
+	<-f.ready
+
+	if f.stream != nil {
+		f.owner.detach(f)
+		_ = f.stream.Close()
+	}
+
+	<-f.done
+}
+
+// Close detaches the reader. Closing the last reader stops ffmpeg.
+func (s *subscriber) Close() error {
+	s.closeOnce.Do(func() {
+		_ = s.PipeReader.Close()
+		s.feed.leave(s)
+	})
+
+	return nil
+}
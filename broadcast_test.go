@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcasterFanOut(t *testing.T) {
+	t.Parallel()
+
+	broadcast := Get(nil).Broadcaster(context.Background())
+	current, starting, err := broadcast.feed("cam")
+	require.NoError(t, err)
+	require.True(t, starting)
+
+	// Stand in for ffmpeg.
+	stream, camera := io.Pipe()
+	current.stream = stream
+	close(current.ready)
+
+	go current.run()
+
+	init := append(testBox("ftyp", "isom"), testBox("moov", "tracks")...)
+	one := append(testBox("moof", "one"), testBox("mdat", "frames1")...)
+	two := append(testBox("moof", "two"), testBox("mdat", "frames2")...)
+
+	first := current.subscribe()
+	_, _ = camera.Write(append(bytes.Clone(init), one...))
+	require.Equal(t, append(bytes.Clone(init), one...), readN(t, first, len(init)+len(one)))
+
+	_, second, err := broadcast.Subscribe("cam", "")
+	require.NoError(t, err)
+
+	_, _ = camera.Write(two)
+	require.Equal(t, two, readN(t, first, len(two)))
+	require.Equal(t, append(bytes.Clone(init), two...), readN(t, second, len(init)+len(two)),
+		"a new reader starts with the init segment and the next fragment")
+
+	require.NoError(t, first.Close())
+	require.Contains(t, broadcast.feeds, "cam", "the process runs while a reader is open")
+	require.NoError(t, second.Close())
+	require.NotContains(t, broadcast.feeds, "cam", "the process stops with the last reader")
+	<-current.done
+}
+
+func TestBroadcasterSlowReader(t *testing.T) {
+	t.Parallel()
+
+	broadcast := Get(nil).Broadcaster(context.Background())
+	current, _, err := broadcast.feed("cam")
+	require.NoError(t, err)
+
+	stream, camera := io.Pipe()
+	current.stream = stream
+	close(current.ready)
+
+	go current.run()
+
+	_, _ = camera.Write(append(testBox("ftyp", "isom"), testBox("moov", "tracks")...))
+
+	// The only reader never reads. Once it falls behind, it is dropped and ffmpeg stops.
+	reader := current.subscribe()
+	fragment := append(testBox("moof", "fragment"), testBox("mdat", "frames")...)
+
+	for {
+		if _, err = camera.Write(fragment); err != nil {
+			break // the feed closed the stream.
+		}
+	}
+
+	<-current.done
+	require.NotContains(t, broadcast.feeds, "cam", "the process stops when the last reader is dropped")
+
+	_, err = io.ReadAll(reader)
+	require.ErrorIs(t, err, ErrSlowReader)
+	require.NoError(t, reader.Close())
+}
+
+func TestBroadcasterSubscribe(t *testing.T) {
+	t.Parallel()
+
+	broadcast := Get(&Config{FFMPEG: "echo"}).Broadcaster(context.Background())
+	cmd, reader, err := broadcast.Subscribe("rtsp://example.local/stream", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f mp4 -movflags frag_keyframe+empty_moov")
+	require.NotContains(t, cmd, "-t ", "broadcasts must not be limited by capture time")
+
+	// echo does not produce an mp4.
+	_, err = io.ReadAll(reader)
+	require.ErrorIs(t, err, ErrInvalidMP4)
+	require.NoError(t, reader.Close())
+
+	_, _, err = broadcast.Subscribe("", "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	require.NoError(t, broadcast.Close())
+	_, _, err = broadcast.Subscribe("rtsp://example.local/stream", "")
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func readN(t *testing.T, reader io.Reader, size int) []byte {
+	t.Helper()
+
+	data := make([]byte, size)
+	_, err := io.ReadFull(reader, data)
+	require.NoError(t, err)
+
+	return data
+}
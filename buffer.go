@@ -0,0 +1,287 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fragments waiting to be written to a clip reader before it is considered too slow.
+const clipBuffer = 256
+
+// Buffer keeps the most recent seconds of a live stream in memory, so clips can
+// include what happened before they were requested. Create one with Encoder.BufferVideo().
+type Buffer struct {
+	cmdStr  string
+	preRoll time.Duration
+	ctx     context.Context //nolint:containedctx // used to tell a canceled stream from a failed one.
+	stream  io.ReadCloser
+	closed  atomic.Bool
+	mu      sync.Mutex
+	init    []byte
+	ring    []fragment
+	clips   map[*clipReader]struct{}
+	done    chan struct{}
+	err     error
+}
+
+// fragment is one moof+mdat pair and the time it arrived.
+type fragment struct {
+	at   time.Time
+	data []byte
+}
+
+// clipReader is a reader waiting for post-roll fragments.
+type clipReader struct {
+	until  time.Time
+	chunks chan []byte
+	stop   chan struct{} // closed if the reader falls behind.
+}
+
+// BufferVideo starts a long-lived ffmpeg process that keeps the last preRoll of video from
+// input in memory, as fragmented MP4. Call Clip() or SaveClip() when an event happens to get
+// the pre-roll plus any amount of post-roll in one file. Time and Size from the config do not
+// apply; the buffer runs until it is closed or the context is canceled. Pre-roll is kept in
+// whole fragments, so it may reach back one keyframe interval further than requested.
+// Returns command used for diagnostics, the running Buffer and error or nil.
+//
+//nolint:contextcheck // caller-provided context is accepted and used for command execution.
+func (e *Encoder) BufferVideo(ctx context.Context, input, title string, preRoll time.Duration) (string, *Buffer, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if err := e.checkFilters(); err != nil {
+		return "", nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdCtx, cmdCancel := context.WithCancel(ctx)
+	cmdStr, cmd := e.getVideoHandle(cmdCtx, &videoJob{input: input, output: "-", title: title})
+
+	stream, err := startStream(cmd, cmdCancel, e.newStderr())
+	if err != nil {
+		return cmdStr, nil, err
+	}
+
+	buffer := newBuffer(ctx, preRoll)
+	buffer.cmdStr = cmdStr
+	buffer.stream = stream
+
+	go buffer.run()
+
+	return cmdStr, buffer, nil
+}
+
+func newBuffer(ctx context.Context, preRoll time.Duration) *Buffer {
+	return &Buffer{
+		ctx:     ctx,
+		preRoll: preRoll,
+		clips:   make(map[*clipReader]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Command returns the command used for diagnostics.
+func (b *Buffer) Command() string {
+	return b.cmdStr
+}
+
+// Clip returns a reader that produces one fragmented MP4 containing the buffered pre-roll
+// followed by postRoll of live video. The reader returns io.EOF when postRoll has elapsed.
+// If the stream already ended, the reader produces only what is buffered.
+// Close the reader to abandon the clip early.
+func (b *Buffer) Clip(postRoll time.Duration) io.ReadCloser {
+	reader, writer := io.Pipe()
+	newClip := &clipReader{
+		until:  time.Now().Add(postRoll),
+		chunks: make(chan []byte, clipBuffer),
+		stop:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	chunks := make([][]byte, 0, len(b.ring)+1)
+	if b.init != nil {
+		chunks = append(chunks, b.init)
+	}
+
+	for _, frag := range b.ring {
+		chunks = append(chunks, frag.data)
+	}
+
+	b.clips[newClip] = struct{}{}
+	go b.feed(newClip, writer, chunks)
+
+	return reader
+}
+
+// SaveClip writes the buffered pre-roll followed by postRoll of live video to a file.
+// The file is fragmented MP4 and will be overwritten. Cancel the context to stop early.
+func (b *Buffer) SaveClip(ctx context.Context, output string, postRoll time.Duration) error {
+	if output == "" || output == "-" {
+		return ErrInvalidOutput
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("creating clip file: %w", err)
+	}
+	defer file.Close()
+
+	reader := b.Clip(postRoll)
+	defer reader.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = reader.Close() })
+	defer stop()
+
+	if _, err = io.Copy(file, reader); err != nil {
+		return fmt.Errorf("writing clip file: %w", err)
+	}
+
+	return nil
+}
+
+// Wait blocks until the stream stops. Returns nil if it was stopped by Close() or
+// its context, or the ffmpeg failure if the stream ended any other way.
+func (b *Buffer) Wait() error {
+	<-b.done
+
+	return b.err
+}
+
+// Close stops ffmpeg and ends every clip in progress.
+func (b *Buffer) Close() error {
+	b.closed.Store(true)
+	err := b.stream.Close()
+	<-b.done
+
+	return err
+}
+
+// run reads the stream until it ends.
+func (b *Buffer) run() {
+	defer close(b.done)
+
+	err := readFragments(b.stream, b.setInit, func(data []byte) { b.push(time.Now(), data) })
+	closeErr := b.stream.Close()
+
+	if b.closed.Load() || b.ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		b.err = err
+	} else {
+		b.err = closeErr
+	}
+}
+
+func (b *Buffer) setInit(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.init = data
+
+	for clip := range b.clips {
+		b.send(clip, data)
+	}
+}
+
+// push adds a fragment to the ring, expires old ones and hands it to clips that want it.
+func (b *Buffer) push(now time.Time, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ring = append(b.ring, fragment{at: now, data: data})
+
+	// A fragment arrives when its last frame is written, so it is expired once it arrived before the cutoff.
+	cutoff := now.Add(-b.preRoll)
+	for len(b.ring) > 1 && b.ring[0].at.Before(cutoff) {
+		b.ring[0] = fragment{}
+		b.ring = b.ring[1:]
+	}
+
+	for clip := range b.clips {
+		if now.Before(clip.until) {
+			b.send(clip, data)
+		}
+	}
+}
+
+// send gives a clip a chunk without blocking. A clip that cannot keep up is stopped.
+// Must be called with the lock held.
+func (b *Buffer) send(clip *clipReader, data []byte) {
+	select {
+	case clip.chunks <- data:
+	default:
+		delete(b.clips, clip)
+		close(clip.stop)
+	}
+}
+
+// feed writes a clip to its reader until post-roll elapses, the stream ends or the reader goes away.
+func (b *Buffer) feed(clip *clipReader, writer *io.PipeWriter, chunks [][]byte) {
+	defer b.removeClip(clip)
+
+	for _, chunk := range chunks {
+		if _, err := writer.Write(chunk); err != nil {
+			return // reader closed.
+		}
+	}
+
+	timer := time.NewTimer(time.Until(clip.until))
+	defer timer.Stop()
+
+	for {
+		select {
+		case chunk := <-clip.chunks:
+			if _, err := writer.Write(chunk); err != nil {
+				return
+			}
+		case <-clip.stop:
+			writer.CloseWithError(ErrSlowReader)
+
+			return
+		case <-timer.C:
+			finishPipe(writer, clip.chunks, nil)
+
+			return
+		case <-b.done:
+			finishPipe(writer, clip.chunks, b.err)
+
+			return
+		}
+	}
+}
+
+// finishPipe writes any queued chunks and closes the reader with err, or io.EOF if err is nil.
+func finishPipe(writer *io.PipeWriter, chunks <-chan []byte, err error) {
+	for {
+		select {
+		case chunk := <-chunks:
+			if _, werr := writer.Write(chunk); werr != nil {
+				return
+			}
+		default:
+			writer.CloseWithError(err)
+
+			return
+		}
+	}
+}
+
+func (b *Buffer) removeClip(clip *clipReader) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.clips, clip)
+}
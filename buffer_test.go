@@ -0,0 +1,55 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferPreRoll(t *testing.T) {
+	t.Parallel()
+
+	buffer := newBuffer(context.Background(), 10*time.Second)
+	start := time.Now()
+
+	buffer.setInit([]byte("init,"))
+
+	for idx, data := range []string{"f0,", "f1,", "f2,", "f3,"} {
+		buffer.push(start.Add(time.Duration(idx)*4*time.Second), []byte(data))
+	}
+
+	// f0 finished arriving 12 seconds before f3; f1 holds the video from 8 to 12 seconds ago.
+	require.Len(t, buffer.ring, 3)
+
+	clip := buffer.Clip(time.Hour)
+	buffer.push(time.Now(), []byte("live"))
+	close(buffer.done) // pretend the stream ended.
+
+	data, err := io.ReadAll(clip)
+	require.NoError(t, err)
+	require.Equal(t, "init,f1,f2,f3,live", string(data))
+}
+
+func TestBufferVideo(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo"})
+	cmd, buffer, err := encode.BufferVideo(context.Background(), "rtsp://example.local/stream", "", 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, cmd, buffer.Command())
+	require.Contains(t, cmd, "-f mp4 -movflags frag_keyframe+empty_moov")
+	require.NotContains(t, cmd, "-t ", "buffers must not be limited by capture time")
+
+	// echo does not produce an mp4.
+	require.ErrorIs(t, buffer.Wait(), ErrInvalidMP4)
+
+	_, err = io.ReadAll(buffer.Clip(time.Second))
+	require.ErrorIs(t, err, ErrInvalidMP4)
+	require.NoError(t, buffer.Close())
+
+	_, _, err = encode.BufferVideo(context.Background(), "", "", time.Second)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,297 @@
+// Package main is ffcam, a command line tool that captures video from cameras with the golift.io/ffmpeg library.
+//
+// Usage:
+//
+//	ffcam record   [flags] INPUT OUTPUT    save a clip, or continuous segments with -segment.
+//	ffcam stream   [flags] INPUT           write fragmented MP4 to stdout.
+//	ffcam snapshot [flags] INPUT OUTPUT    save one frame; OUTPUT "-" writes a JPEG to stdout.
+//	ffcam probe    [flags] INPUT           print the input's streams as JSON.
+//
+// The ffmpeg command is printed to stderr for diagnostics. Exit codes follow sysexits.h:
+// 64 usage error, 65 invalid input data, 69 codec or stream not available, 75 timeout or
+// connection refused (try again), 77 unauthorized. Other ffmpeg failures exit with the ffmpeg exit code, or 1.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golift.io/ffmpeg"
+)
+
+// Exit codes from sysexits.h.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 64
+	exitDataErr     = 65
+	exitUnavailable = 69
+	exitTempFail    = 75
+	exitNoPerm      = 77
+)
+
+// flags are the encoder settings shared by every subcommand.
+// Values are strings, so the library's setters validate them and apply its defaults.
+type flags struct {
+	ffmpeg, ffprobe          string
+	codec, profile, level    string
+	width, height, crf, rate string
+	time, size, scale        string
+	audio, audioCodec        string
+	copy                     bool
+	title                    string
+	segment                  time.Duration
+	strftime                 bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+// run parses the arguments, runs a subcommand and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: ffcam record|stream|snapshot|probe [flags] INPUT [OUTPUT]")
+
+		return exitUsage
+	}
+
+	cmd, opts := args[0], &flags{}
+	set := opts.flagSet(cmd, stderr)
+
+	if err := set.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	usage := map[string]string{"record": "INPUT OUTPUT", "stream": "INPUT", "snapshot": "INPUT OUTPUT", "probe": "INPUT"}
+	if want, ok := usage[cmd]; !ok || set.NArg() != len(strings.Fields(want)) {
+		fmt.Fprintf(stderr, "usage: ffcam %s [flags] %s\n", cmd, want)
+		set.PrintDefaults()
+
+		return exitUsage
+	}
+
+	encode, err := opts.encoder()
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+
+		return exitUsage
+	}
+
+	input := set.Arg(0)
+
+	var once sync.Once
+	// Long-running commands print the command when they start; the others print it when they finish.
+	announce := func(cmdStr string) {
+		if cmdStr != "" {
+			once.Do(func() { fmt.Fprintln(stderr, cmdStr) })
+		}
+	}
+
+	var cmdStr string
+
+	switch cmd {
+	case "record":
+		cmdStr, err = opts.record(ctx, encode, input, set.Arg(1), stdout, announce)
+	case "stream":
+		cmdStr, err = stream(ctx, encode, input, opts.title, stdout, announce)
+	case "snapshot":
+		cmdStr, err = snapshot(ctx, encode, input, set.Arg(1), stdout)
+	case "probe":
+		cmdStr, err = probe(ctx, encode, input, stdout)
+	}
+
+	announce(cmdStr)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+
+		return exitCode(err)
+	}
+
+	return exitOK
+}
+
+func (f *flags) flagSet(name string, output io.Writer) *flag.FlagSet {
+	set := flag.NewFlagSet("ffcam "+name, flag.ContinueOnError)
+	set.SetOutput(output)
+	set.StringVar(&f.ffmpeg, "ffmpeg", ffmpeg.DefaultFFmpegPath, "path to ffmpeg")
+	set.StringVar(&f.ffprobe, "ffprobe", ffmpeg.DefaultFFprobePath, "path to ffprobe")
+	set.StringVar(&f.codec, "codec", ffmpeg.DefaultCodec,
+		"video encoder: libx264, libx265, libvpx-vp9, libaom-av1, libsvtav1")
+	set.StringVar(&f.profile, "profile", "", "encoder profile (default depends on codec)")
+	set.StringVar(&f.level, "level", "", "encoder level (default depends on codec)")
+	set.StringVar(&f.width, "width", "", "frame width")
+	set.StringVar(&f.height, "height", "", "frame height")
+	set.StringVar(&f.crf, "crf", "", "quality; lower is better (range depends on codec)")
+	set.StringVar(&f.rate, "rate", "", "frames per second")
+	set.StringVar(&f.time, "time", "", "maximum capture length in seconds")
+	set.StringVar(&f.size, "size", "", "maximum capture size in bytes")
+	set.StringVar(&f.scale, "scale", "", "scale mode: stretch, fit, fill, fit-width, fit-height")
+	set.StringVar(&f.audio, "audio", "false", "include audio")
+	set.StringVar(&f.audioCodec, "audio-codec", "", "audio codec: copy, auto, aac, opus, mp3")
+	set.BoolVar(&f.copy, "copy", false, "copy the original video, rather than transcode it")
+	set.StringVar(&f.title, "title", "", "title encoded into the video")
+	set.DurationVar(&f.segment, "segment", 0, "record: split a continuous recording into files this long")
+	set.BoolVar(&f.strftime, "strftime", false, "record: OUTPUT is a strftime pattern, rather than a %d pattern")
+
+	return set
+}
+
+// encoder builds an encoder. The setters run in order, so the codec is known before its profile and level.
+// Every invalid flag is reported, rather than replaced with a default.
+func (f *flags) encoder() (*ffmpeg.Encoder, error) {
+	encode := ffmpeg.Get(&ffmpeg.Config{FFMPEG: f.ffmpeg, FFProbe: f.ffprobe, Copy: f.copy})
+	// Calls in a composite literal run in order.
+	errs := []error{
+		check(encode.SetCodecStrict(f.codec)),
+		check(encode.SetProfileStrict(f.profile)),
+		check(encode.SetLevelStrict(f.level)),
+		check(encode.SetWidthStrict(f.width)),
+		check(encode.SetHeightStrict(f.height)),
+		check(encode.SetCRFStrict(f.crf)),
+		check(encode.SetRateStrict(f.rate)),
+		check(encode.SetTimeStrict(f.time)),
+		check(encode.SetSizeStrict(f.size)),
+		check(encode.SetScaleStrict(f.scale)),
+		check(encode.SetAudioStrict(f.audio)),
+		check(encode.SetAudioCodecStrict(f.audioCodec)),
+	}
+
+	return encode, errors.Join(errs...)
+}
+
+// check returns the error from a strict setter.
+func check[T any](_ T, err error) error {
+	return err
+}
+
+// record saves one clip, or with -segment, records segments until interrupted.
+func (f *flags) record(
+	ctx context.Context, encode *ffmpeg.Encoder, input, output string, stdout io.Writer, announce func(string),
+) (string, error) {
+	if f.segment <= 0 {
+		cmdStr, _, err := encode.SaveVideoContext(ctx, input, output, f.title)
+
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	cmdStr, recorder, err := encode.Record(ctx, input, &ffmpeg.Recording{
+		Template: output,
+		Segment:  f.segment,
+		Strftime: f.strftime,
+		Title:    f.title,
+	})
+	if err != nil {
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	announce(cmdStr)
+
+	for segment := range recorder.Segments() {
+		fmt.Fprintln(stdout, segment.Path)
+	}
+
+	return cmdStr, recorder.Wait() //nolint:wrapcheck // exitCode() needs the library error.
+}
+
+// stream writes video to stdout until it ends.
+func stream(
+	ctx context.Context, encode *ffmpeg.Encoder, input, title string, stdout io.Writer, announce func(string),
+) (string, error) {
+	cmdStr, video, err := encode.GetVideoContext(ctx, input, title)
+	if err != nil {
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	announce(cmdStr)
+
+	_, copyErr := io.Copy(stdout, video)
+	if err = video.Close(); err != nil {
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	if copyErr != nil {
+		return cmdStr, fmt.Errorf("writing stream: %w", copyErr)
+	}
+
+	return cmdStr, nil
+}
+
+// snapshot saves one frame, or writes a JPEG to stdout if output is "-".
+func snapshot(ctx context.Context, encode *ffmpeg.Encoder, input, output string, stdout io.Writer) (string, error) {
+	if output != "-" {
+		cmdStr, _, err := encode.SaveSnapshot(ctx, input, output)
+
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	cmdStr, image, err := encode.GetSnapshot(ctx, input)
+	if err != nil {
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	if _, err = stdout.Write(image); err != nil {
+		return cmdStr, fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	return cmdStr, nil
+}
+
+// probe prints what ffprobe found as JSON.
+func probe(ctx context.Context, encode *ffmpeg.Encoder, input string, stdout io.Writer) (string, error) {
+	cmdStr, result, err := encode.Probe(ctx, input)
+	if err != nil {
+		return cmdStr, err //nolint:wrapcheck // exitCode() needs the library error.
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(result); err != nil {
+		return cmdStr, fmt.Errorf("writing probe result: %w", err)
+	}
+
+	return cmdStr, nil
+}
+
+// exitCode turns a failure into an exit code that scripts can act on.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, ffmpeg.ErrInvalidInput), errors.Is(err, ffmpeg.ErrInvalidOutput),
+		errors.Is(err, ffmpeg.ErrInvalidMask), errors.Is(err, ffmpeg.ErrCopyFilter):
+		return exitUsage
+	case errors.Is(err, ffmpeg.ErrInvalidData):
+		return exitDataErr
+	case errors.Is(err, ffmpeg.ErrUnknownCodec), errors.Is(err, ffmpeg.ErrStreamNotFound),
+		errors.Is(err, ffmpeg.ErrNoSuchFile):
+		return exitUnavailable
+	case errors.Is(err, ffmpeg.ErrTimeout), errors.Is(err, ffmpeg.ErrConnectionRefused):
+		return exitTempFail
+	case errors.Is(err, ffmpeg.ErrUnauthorized):
+		return exitNoPerm
+	}
+
+	var ffErr *ffmpeg.FFmpegError
+	if errors.As(err, &ffErr) && ffErr.ExitCode > 0 {
+		return ffErr.ExitCode
+	}
+
+	return exitFailure
+}
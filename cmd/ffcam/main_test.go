@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golift.io/ffmpeg"
+)
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	require.Equal(t, exitUsage, run(context.Background(), nil, &stdout, &stderr))
+	require.Equal(t, exitUsage, run(context.Background(), []string{"dance", "INPUT"}, &stdout, &stderr))
+	require.Equal(t, exitUsage, run(context.Background(), []string{"record", "INPUT"}, &stdout, &stderr))
+	require.Equal(t, exitUsage, run(context.Background(), []string{"probe", "-nope", "INPUT"}, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "usage: ffcam record [flags] INPUT OUTPUT")
+	require.Empty(t, stdout.String())
+}
+
+func TestRunInvalidFlags(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	args := []string{"stream", "-ffmpeg", "echo", "-width", "abc", "-level", "9.9", "INPUT"}
+	require.Equal(t, exitUsage, run(context.Background(), args, &stdout, &stderr))
+	require.Contains(t, stderr.String(), `width "abc"`)
+	require.Contains(t, stderr.String(), `level "9.9"`)
+	require.Empty(t, stdout.String(), "nothing runs with invalid flags")
+}
+
+func TestRunStream(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	args := []string{"stream", "-ffmpeg", "echo", "-rate", "10", "-width", "640", "-height", "480", "INPUT"}
+	require.Equal(t, exitOK, run(context.Background(), args, &stdout, &stderr))
+	require.Contains(t, stdout.String(), "-i INPUT")
+	require.Contains(t, stdout.String(), "-r 10")
+	require.Contains(t, stdout.String(), "-s 640x480")
+	require.Equal(t, 1, bytes.Count(stderr.Bytes(), []byte("echo ")), "the command is printed once")
+}
+
+func TestRunFailure(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	args := []string{"snapshot", "-ffmpeg", "false", "INPUT", "-"}
+	require.Equal(t, exitFailure, run(context.Background(), args, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "false ")
+	require.Contains(t, stderr.String(), "error:")
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, exitOK, exitCode(nil))
+	require.Equal(t, exitUsage, exitCode(ffmpeg.ErrInvalidInput))
+	require.Equal(t, exitUsage, exitCode(fmt.Errorf("wrapped: %w", ffmpeg.ErrCopyFilter)))
+	require.Equal(t, exitFailure, exitCode(context.Canceled))
+
+	tests := map[error]int{
+		ffmpeg.ErrInvalidData:       exitDataErr,
+		ffmpeg.ErrUnknownCodec:      exitUnavailable,
+		ffmpeg.ErrStreamNotFound:    exitUnavailable,
+		ffmpeg.ErrNoSuchFile:        exitUnavailable,
+		ffmpeg.ErrTimeout:           exitTempFail,
+		ffmpeg.ErrConnectionRefused: exitTempFail,
+		ffmpeg.ErrUnauthorized:      exitNoPerm,
+		nil:                         3, // unclassified; the ffmpeg exit code is used.
+	}
+
+	for kind, code := range tests {
+		require.Equal(t, code, exitCode(&ffmpeg.FFmpegError{Kind: kind, ExitCode: 3}), kind)
+	}
+
+	require.Equal(t, exitFailure, exitCode(&ffmpeg.FFmpegError{ExitCode: -1}))
+}
@@ -0,0 +1,164 @@
+package ffmpeg
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Video encoders this library knows how to configure. Use one of these for Config.Codec.
+const (
+	CodecH264   = "libx264"
+	CodecH265   = "libx265"
+	CodecVP9    = "libvpx-vp9"
+	CodecAV1    = "libaom-av1"
+	CodecSVTAV1 = "libsvtav1"
+)
+
+// DefaultCodec is used when Config.Codec is empty or unknown.
+//
+//nolint:gochecknoglobals // this is a constant, not a variable, but configurable by a consumer.
+var DefaultCodec = CodecH264
+
+// Codec describes what a video encoder accepts and how it is invoked.
+// Quality values are CRF, lower is better. Get one with LookupCodec().
+type Codec struct {
+	Name           string   // ffmpeg encoder name, like libx264.
+	Profiles       []string // valid profiles. Empty if the profile is not configurable.
+	DefaultProfile string
+	Levels         []string // valid levels. Empty if the level is not configurable.
+	DefaultLevel   string
+	MinimumCRF     int
+	MaximumCRF     int
+	DefaultCRF     int
+	Container      string            // ffmpeg format for file output: mov, webm, mp4.
+	PixelFormats   map[string]string // pixel format for profiles that need more than yuv420p.
+	Options        []string          // encoder speed and rate control options.
+	levelArgs      func(level string) []string
+}
+
+// LookupCodec returns the settings for a video encoder, and false if it is not one this library knows.
+// The libx264 settings come from DefaultProfile, DefaultLevel and the *EncodeCRF package variables.
+func LookupCodec(name string) (Codec, bool) { //nolint:funlen // it's a lookup table.
+	switch name {
+	case CodecH264:
+		return Codec{
+			Name:           CodecH264,
+			Profiles:       []string{"baseline", "main", "high"},
+			DefaultProfile: DefaultProfile,
+			Levels:         []string{"3.0", "3.1", "4.0", "4.1", "4.2"},
+			DefaultLevel:   DefaultLevel,
+			MinimumCRF:     MinimumEncodeCRF,
+			MaximumCRF:     MaximumEncodeCRF,
+			DefaultCRF:     DefaultEncodeCRF,
+			Container:      "mov",
+			Options:        []string{"-preset", "superfast"},
+			levelArgs:      func(level string) []string { return []string{"-level", level} },
+		}, true
+	case CodecH265:
+		return Codec{
+			Name:           CodecH265,
+			Profiles:       []string{"main", "main10"},
+			DefaultProfile: "main",
+			Levels:         []string{"3.0", "3.1", "4.0", "4.1", "5.0", "5.1"},
+			DefaultLevel:   "4.0",
+			MinimumCRF:     18,
+			MaximumCRF:     35,
+			DefaultCRF:     26,
+			Container:      "mov",
+			PixelFormats:   map[string]string{"main10": "yuv420p10le"},
+			// hvc1 lets Apple players open the file.
+			Options:   []string{"-preset", "superfast", "-tag:v", "hvc1"},
+			levelArgs: func(level string) []string { return []string{"-x265-params", "level-idc=" + level} },
+		}, true
+	case CodecVP9:
+		return Codec{
+			Name:           CodecVP9,
+			Profiles:       []string{"0", "2"},
+			DefaultProfile: "0",
+			MinimumCRF:     15,
+			MaximumCRF:     50,
+			DefaultCRF:     31,
+			Container:      "webm",
+			PixelFormats:   map[string]string{"2": "yuv420p10le"},
+			// A zero bitrate makes CRF the only rate control.
+			Options: []string{"-b:v", "0", "-deadline", "realtime", "-cpu-used", "8", "-row-mt", "1"},
+		}, true
+	case CodecAV1:
+		return Codec{
+			Name:           CodecAV1,
+			Profiles:       []string{"main"},
+			DefaultProfile: "main",
+			MinimumCRF:     15,
+			MaximumCRF:     50,
+			DefaultCRF:     30,
+			Container:      "mp4",
+			Options:        []string{"-b:v", "0", "-usage", "realtime", "-cpu-used", "8", "-row-mt", "1"},
+		}, true
+	case CodecSVTAV1:
+		return Codec{
+			Name:           CodecSVTAV1,
+			Profiles:       []string{"main"},
+			DefaultProfile: "main",
+			MinimumCRF:     20,
+			MaximumCRF:     50,
+			DefaultCRF:     35,
+			Container:      "mp4",
+			Options:        []string{"-preset", "10"},
+		}, true
+	default:
+		return Codec{}, false
+	}
+}
+
+// SetCodec sets the video encoder. Unknown values are replaced with DefaultCodec.
+// The profile, level and CRF are re-checked against the new encoder.
+// This can also be passed into Get().
+func (e *Encoder) SetCodec(codec string) string {
+	if _, ok := LookupCodec(codec); !ok {
+		codec = DefaultCodec
+	}
+
+	e.config.Codec = codec
+	e.SetProfile(e.config.Prof)
+	e.SetLevel(e.config.Level)
+	e.fixValues()
+
+	return e.config.Codec
+}
+
+// codec returns the settings for the configured video encoder.
+func (e *Encoder) codec() Codec {
+	return e.config.Limits.codec(e.config.Codec)
+}
+
+// pixelFormat returns the pixel format a profile needs.
+func (c *Codec) pixelFormat(profile string) string {
+	if format, ok := c.PixelFormats[profile]; ok {
+		return format
+	}
+
+	return "yuv420p"
+}
+
+// videoArgs returns the encoder options for the configured profile, level and quality.
+// Size is the scaling option, or the filter chain that includes scaling.
+func (c *Codec) videoArgs(config *Config, size []string) []string {
+	arg := []string{"-vcodec", c.Name}
+
+	if config.Prof != "" && slices.Contains(c.Profiles, config.Prof) {
+		arg = append(arg, "-profile:v", config.Prof)
+	}
+
+	if config.Level != "" && c.levelArgs != nil && slices.Contains(c.Levels, config.Level) {
+		arg = append(arg, c.levelArgs(config.Level)...)
+	}
+
+	arg = append(arg, "-pix_fmt", c.pixelFormat(config.Prof))
+	arg = append(arg, size...)
+	arg = append(arg, c.Options...)
+
+	return append(arg,
+		"-crf", strconv.Itoa(config.CRF),
+		"-r", strconv.Itoa(config.Rate),
+	)
+}
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCodec(t *testing.T) {
+	t.Parallel()
+
+	asert := assert.New(t)
+	encode := Get(&Config{})
+
+	asert.Equal(CodecH264, encode.Config().Codec)
+	asert.Equal(CodecH264, encode.SetCodec("nope"))
+
+	asert.Equal(CodecH265, encode.SetCodec(CodecH265))
+	asert.Equal("main", encode.Config().Prof, "the h264 profile must be replaced with a valid h265 profile")
+	asert.Equal("3.0", encode.Config().Level, "3.0 is valid for both codecs")
+	asert.Equal("main10", encode.SetProfile("main10"))
+	asert.Equal("main", encode.SetProfile("high"))
+	asert.Equal("5.1", encode.SetLevel("5.1"))
+	asert.Equal(35, encode.SetCRF("40"))
+
+	asert.Equal(CodecVP9, encode.SetCodec(CodecVP9))
+	asert.Equal("0", encode.Config().Prof)
+	asert.Empty(encode.Config().Level, "vp9 has no configurable level")
+	asert.Equal(35, encode.Config().CRF, "35 is valid for vp9")
+	asert.Equal(50, encode.SetCRF("60"))
+}
+
+func TestCodecArgs(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Codec: CodecH265, Prof: "main10", Level: "4.1"})
+	cmd, _, err := encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f mov")
+	require.Contains(t, cmd, "-vcodec libx265 -profile:v main10 -x265-params level-idc=4.1 -pix_fmt yuv420p10le")
+	require.Contains(t, cmd, "-tag:v hvc1 -crf 26")
+	require.Contains(t, cmd, "-movflags faststart")
+
+	encode = Get(&Config{FFMPEG: "echo", Codec: CodecVP9})
+	cmd, _, err = encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.webm", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f webm")
+	require.Contains(t, cmd, "-vcodec libvpx-vp9 -profile:v 0 -pix_fmt yuv420p")
+	require.Contains(t, cmd, "-b:v 0")
+	require.NotContains(t, cmd, "-level")
+	require.NotContains(t, cmd, "-movflags", "webm does not use movflags")
+
+	encode = Get(&Config{FFMPEG: "echo", Codec: CodecSVTAV1, Copy: true})
+	cmd, _, err = encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f mov", "copied streams always go into mov")
+	require.NotContains(t, cmd, "libsvtav1")
+}
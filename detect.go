@@ -0,0 +1,229 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Motion detection defaults. Change these if your needs differ.
+//
+//nolint:gochecknoglobals,mnd // these are constants, not variables, but configurable by a consumer.
+var (
+	DefaultMotionThreshold = 0.02             // scene change score, from 0 (identical) to 1 (different).
+	DefaultMotionRate      = 2                // frames analyzed per second.
+	DefaultMotionCooldown  = 10 * time.Second // quiet time after an event.
+	DefaultMotionWidth     = 320              // frames are scaled down to this width before they are compared.
+)
+
+// Motion events waiting to be received on Detector.Events().
+const motionBuffer = 10
+
+// Detection defines how a Detector looks for motion. Zero values use the Default* motion values.
+type Detection struct {
+	Threshold float64       // scene change score that counts as motion.
+	Rate      int           // frames analyzed per second. Lower is cheaper.
+	Cooldown  time.Duration // events closer together than this are dropped.
+	// OnMotion is called in its own goroutine for every event. The context is canceled when
+	// the detector stops, whether its context was canceled or ffmpeg exited on its own.
+	// Use it to start a recording, like SaveVideoContext, when motion is seen.
+	OnMotion func(ctx context.Context, event MotionEvent)
+}
+
+// MotionEvent is a frame that changed more than the threshold from the frame before it.
+type MotionEvent struct {
+	Time   time.Time     // when the frame was analyzed.
+	Offset time.Duration // frame timestamp from the start of the stream.
+	Score  float64       // scene change score: 0 to 1.
+}
+
+// Detector is a running motion detector. Create one with Encoder.Detect().
+type Detector struct {
+	cmdStr string
+	events chan MotionEvent
+	done   chan struct{}
+	err    error
+}
+
+// motionParser turns the metadata ffmpeg prints for selected frames into events.
+type motionParser struct {
+	cooldown time.Duration
+	offset   time.Duration // timestamp of the frame being printed.
+	last     time.Duration // timestamp of the last event.
+	seen     bool          // an event was produced, so last is valid.
+}
+
+// Detect starts a long-lived ffmpeg process that compares frames of an input and reports motion.
+// Only a few small frames per second are analyzed, so this is cheap compared to recording.
+// Codec, frame size and filter settings from the config do not apply.
+// Returns command used for diagnostics, the running Detector and error or nil.
+func (e *Encoder) Detect(ctx context.Context, input string, det *Detection) (string, *Detector, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	settings := Detection{}
+	if det != nil {
+		settings = *det
+	}
+
+	// OnMotion callbacks get this context, so they stop when the detector stops for any reason.
+	ctx, cancel := context.WithCancel(ctx)
+	cmdStr, cmd := e.getDetectHandle(ctx, input, &settings)
+
+	stderr := e.newStderr()
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+
+		return cmdStr, nil, fmt.Errorf("subcommand failed: %w", err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		cancel()
+
+		return cmdStr, nil, withStderr("run failed", err, stderr.String())
+	}
+
+	detector := &Detector{
+		cmdStr: cmdStr,
+		events: make(chan MotionEvent, motionBuffer),
+		done:   make(chan struct{}),
+	}
+
+	go detector.watch(ctx, cancel, cmd, stdout, stderr, &settings)
+
+	return cmdStr, detector, nil
+}
+
+// Command returns the command used for diagnostics.
+func (d *Detector) Command() string {
+	return d.cmdStr
+}
+
+// Events returns a channel that receives motion events. The channel is closed when the detector stops.
+// Events that are not received promptly are dropped, so the detector never falls behind the stream.
+func (d *Detector) Events() <-chan MotionEvent {
+	return d.events
+}
+
+// Wait blocks until the detector stops. Returns nil if it was stopped by its context,
+// or the ffmpeg failure if the detector ended any other way.
+func (d *Detector) Wait() error {
+	<-d.done
+
+	return d.err
+}
+
+// watch reports events as ffmpeg prints them, then collects the exit status.
+// Cancel is called when ffmpeg exits, before Wait returns, to stop OnMotion callbacks.
+func (d *Detector) watch(
+	ctx context.Context, cancel context.CancelFunc, cmd *exec.Cmd, stdout io.Reader, stderr *tailBuffer, det *Detection,
+) {
+	defer close(d.done)
+	defer cancel()
+
+	parser := &motionParser{cooldown: det.Cooldown}
+	if parser.cooldown == 0 {
+		parser.cooldown = DefaultMotionCooldown
+	}
+
+	for scanner := bufio.NewScanner(stdout); scanner.Scan(); {
+		event, ok := parser.line(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		if det.OnMotion != nil {
+			go det.OnMotion(ctx, event)
+		}
+
+		select {
+		case d.events <- event:
+		default:
+		}
+	}
+
+	close(d.events)
+
+	err := cmd.Wait()
+	if err != nil && ctx.Err() == nil {
+		d.err = runError(ctx, "run failed", err, stderr.String())
+	}
+}
+
+// getDetectHandle creates and returns an ffmpeg command that prints the scene score of changed frames to stdout.
+func (e *Encoder) getDetectHandle(ctx context.Context, input string, det *Detection) (string, *exec.Cmd) {
+	threshold := det.Threshold
+	if threshold <= 0 {
+		threshold = DefaultMotionThreshold
+	}
+
+	rate := det.Rate
+	if rate <= 0 {
+		rate = DefaultMotionRate
+	}
+
+	rate = min(rate, e.config.Limits.MaximumFrameRate)
+
+	filters := []string{
+		"fps=" + strconv.Itoa(rate),
+		"scale=" + strconv.Itoa(DefaultMotionWidth) + ":-2",
+		// The comma is escaped for the filtergraph parser.
+		`select=gt(scene\,` + strconv.FormatFloat(threshold, 'f', -1, bits64) + ")",
+		"metadata=print:file=" + filterValue("pipe:1"),
+	}
+
+	arg := append(e.inputArgs(input),
+		"-an", "-vf", strings.Join(filters, ","),
+		"-f", "null", "-",
+	)
+
+	return e.command(ctx, arg)
+}
+
+// line reads one line of metadata output. A frame looks like this:
+//
+//	frame:12   pts:49152   pts_time:4.096
+//	lavfi.scene_score=0.034567
+func (p *motionParser) line(line string) (MotionEvent, bool) {
+	if strings.HasPrefix(line, "frame:") {
+		for _, field := range strings.Fields(line) {
+			if value, ok := strings.CutPrefix(field, "pts_time:"); ok {
+				p.offset = parseSeconds(value)
+			}
+		}
+
+		return MotionEvent{}, false
+	}
+
+	value, ok := strings.CutPrefix(line, "lavfi.scene_score=")
+	if !ok {
+		return MotionEvent{}, false
+	}
+
+	score, err := strconv.ParseFloat(value, bits64)
+	if err != nil {
+		return MotionEvent{}, false
+	}
+
+	if p.seen && p.offset-p.last < p.cooldown {
+		return MotionEvent{}, false
+	}
+
+	p.last, p.seen = p.offset, true
+
+	return MotionEvent{Time: time.Now(), Offset: p.offset, Score: score}, true
+}
@@ -0,0 +1,80 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMotionParser(t *testing.T) {
+	t.Parallel()
+
+	parser := &motionParser{cooldown: 5 * time.Second}
+
+	var events []MotionEvent
+
+	for _, line := range []string{
+		"frame:0    pts:2048    pts_time:1.0",
+		"lavfi.scene_score=0.051000",
+		"frame:1    pts:6144    pts_time:3.0",
+		"lavfi.scene_score=0.200000", // within the cooldown.
+		"frame:2    pts:14336   pts_time:7.5",
+		"lavfi.scene_score=nope",
+		"lavfi.scene_score=0.030000",
+	} {
+		if event, ok := parser.line(line); ok {
+			events = append(events, event)
+		}
+	}
+
+	require.Len(t, events, 2)
+	require.Equal(t, time.Second, events[0].Offset)
+	require.InDelta(t, 0.051, events[0].Score, 0.0001)
+	require.Equal(t, 7500*time.Millisecond, events[1].Offset)
+	require.InDelta(t, 0.03, events[1].Score, 0.0001)
+	require.False(t, events[1].Time.IsZero())
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Overlay: &Overlay{}})
+
+	cmd, detector, err := encode.Detect(context.Background(), "rtsp://example.local/stream",
+		&Detection{Threshold: 0.1, Rate: 100})
+	require.NoError(t, err)
+	require.Equal(t, cmd, detector.Command())
+	require.Contains(t, cmd, `-an -vf 'fps=60,scale=320:-2,select=gt(scene\,0.1),metadata=print:file=pipe\\:1' -f null -`)
+
+	_, open := <-detector.Events()
+	require.False(t, open, "echo prints no scores")
+	require.NoError(t, detector.Wait())
+
+	_, _, err = encode.Detect(context.Background(), "", nil)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestDetectStopsCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// ffmpeg reports one event and exits on its own, like a camera that dropped the stream.
+	encode := Get(&Config{FFMPEG: fakeFFmpeg(t, "echo 'frame:1 pts:1 pts_time:1.0'; echo 'lavfi.scene_score=0.5'")})
+	stopped := make(chan struct{})
+
+	_, detector, err := encode.Detect(context.Background(), "INPUT", &Detection{
+		OnMotion: func(ctx context.Context, _ MotionEvent) {
+			<-ctx.Done() // a recording would stop here.
+			close(stopped)
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, detector.Wait())
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnMotion context was not canceled when ffmpeg exited")
+	}
+}
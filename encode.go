@@ -12,13 +12,17 @@ import (
 	"net/url"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Default, Maximum and Minimum Values for encoder configuration. Change these if your needs differ.
+// Get() copies them into each Encoder's Config.Limits, which can also be set per Encoder.
 //
 //nolint:gochecknoglobals,mnd // these are constants, not variables, but configurable by a consumer.
 var (
@@ -36,7 +40,9 @@ var (
 	MaximumCaptureTime = 1200             // 10 minute max.
 	DefaultCaptureSize = int64(2500000)   // 2.5MB default (roughly 5-10 seconds)
 	MaximumCaptureSize = int64(104857600) // 100MB max.
+	DefaultSegmentTime = 5 * time.Minute  // continuous recording file length.
 	DefaultFFmpegPath  = "/usr/local/bin/ffmpeg"
+	DefaultFFprobePath = "/usr/local/bin/ffprobe"
 	DefaultProfile     = "main"
 	DefaultLevel       = "3.0"
 )
@@ -45,6 +51,13 @@ var (
 var (
 	ErrInvalidOutput = errors.New("output path is not valid")
 	ErrInvalidInput  = errors.New("input path is not valid")
+	ErrSlowReader    = errors.New("reader did not keep up with the stream")
+	ErrInvalidMP4    = errors.New("invalid fragmented mp4 stream")
+	ErrInvalidJPEG   = errors.New("invalid mjpeg stream")
+	ErrClosed        = errors.New("broadcaster is closed")
+	ErrInvalidMask   = errors.New("mask does not fit in the frame or has an unknown mode")
+	ErrCopyFilter    = errors.New("video filters like overlays and masks need transcoding and cannot be used with copy")
+	ErrInvalidValue  = errors.New("configuration value is not valid")
 )
 
 const (
@@ -57,25 +70,58 @@ const (
 )
 
 // Config defines how ffmpeg shall transcode a stream.
-// If Copy is true, these options are ignored: profile, level, width, height, crf and frame rate.
+// If Copy is true, these options are ignored: codec, profile, level, width, height, scale, crf and frame rate.
 type Config struct {
-	Copy   bool   // Copy original stream, rather than transcode.
-	Audio  bool   // include audio?
-	Width  int    // 1920
-	Height int    // 1080
-	CRF    int    // 24
-	Time   int    // 15 (seconds)
-	Rate   int    // framerate (5-20)
-	Size   int64  // max file size (always goes over). use 2000000 for 2.5MB
-	FFMPEG string // "/usr/local/bin/ffmpeg"
-	Level  string // 3.0, 3.1 ..
-	Prof   string // main, high, baseline
+	Copy          bool   // Copy original stream, rather than transcode.
+	Audio         bool   // include audio?
+	Width         int    // 1920
+	Height        int    // 1080
+	CRF           int    // 24
+	Time          int    // 15 (seconds)
+	Rate          int    // framerate (5-20)
+	Size          int64  // max file size (always goes over). use 2000000 for 2.5MB
+	FFMPEG        string // "/usr/local/bin/ffmpeg"
+	FFProbe       string // "/usr/local/bin/ffprobe"
+	Codec         string // libx264 (default), libx265, libvpx-vp9, libaom-av1, libsvtav1
+	Level         string // 3.0, 3.1 .. (depends on codec)
+	Prof          string // main, high, baseline (depends on codec)
+	AudioCodec    string // copy (default), aac, opus, mp3, auto (runs ffprobe before every capture to decide)
+	AudioBitrate  int    // kilobits per second when transcoding audio: 64, 128 ..
+	AudioRate     int    // sample rate when transcoding audio: 8000, 48000 ..
+	AudioChannels int    // channels when transcoding audio: 1, 2
+	// RedactParams are URL query parameters whose values are hidden in command strings and errors.
+	// DefaultRedactParams is used if this is nil. User info (user:pass@) is always hidden.
+	RedactParams []string
+	// Retry controls how SaveVideoContext and GetVideoContext retry flaky connections.
+	// The zero value does not retry.
+	Retry RetryPolicy
+	// Crop, Rotate (90, 180, 270 degrees clockwise), Transpose and flips fix the picture from
+	// cameras mounted sideways or upside down. They run in that order, before scaling to Width x Height.
+	// If Width and Height are both 0, the output size follows the crop and rotation.
+	Crop      Crop
+	Scale     string // stretch (default), fit, fill, fit-width, fit-height
+	Rotate    int
+	Transpose string // clock, cclock, clock_flip, cclock_flip
+	FlipH     bool   // mirror left to right.
+	FlipV     bool   // mirror top to bottom.
+	// Overlay burns text, like a timestamp and camera name, into transcoded video and snapshots.
+	Overlay *Overlay
+	// Masks hide regions of transcoded video and snapshots, like a neighbor's window.
+	// They are placed in the source frame, before scaling.
+	Masks []Mask
+	// Limits are the defaults and bounds applied to the values above. Zero fields use the package
+	// Default*, Minimum* and Maximum* values; Config() returns the limits in effect.
+	Limits Limits
+	// Progress is called with every progress report from ffmpeg while video is captured.
+	// It is called from another goroutine and should return quickly.
+	Progress func(Progress)
 }
 
 // Encoder is the struct returned by this library.
 // Contains all the bound methods.
 type Encoder struct {
 	config *Config
+	params *regexp.Regexp // matches query parameters to redact.
 }
 
 // Get an encoder interface.
@@ -85,14 +131,27 @@ func Get(config *Config) *Encoder {
 		*cfg = *config
 	}
 
+	cfg.Masks = slices.Clone(cfg.Masks)
+
+	if cfg.Overlay != nil {
+		overlay := *cfg.Overlay
+		cfg.Overlay = &overlay
+	}
+
+	cfg.Limits = cfg.Limits.withDefaults()
+
 	encode := &Encoder{config: cfg}
 	if encode.config.FFMPEG == "" {
 		encode.config.FFMPEG = DefaultFFmpegPath
 	}
 
-	encode.SetLevel(encode.config.Level)
-	encode.SetProfile(encode.config.Prof)
-	encode.fixValues()
+	if encode.config.FFProbe == "" {
+		encode.config.FFProbe = DefaultFFprobePath
+	}
+
+	encode.params = redactParams(encode.config.RedactParams)
+	encode.SetAudioCodec(encode.config.AudioCodec)
+	encode.SetCodec(encode.config.Codec)
 
 	return encode
 }
@@ -110,23 +169,27 @@ func (e *Encoder) SetAudio(audio string) bool {
 	return e.config.Audio
 }
 
-// SetLevel sets the h264 transcode level.
+// SetLevel sets the transcode level. Valid levels depend on the codec.
 // This can also be passed into Get().
 func (e *Encoder) SetLevel(level string) string {
-	if e.config.Level = level; level != "3.0" && level != "3.1" && level != "4.0" && level != "4.1" && level != "4.2" {
-		e.config.Level = DefaultLevel
+	if codec := e.codec(); !slices.Contains(codec.Levels, level) {
+		level = codec.DefaultLevel
 	}
 
+	e.config.Level = level
+
 	return e.config.Level
 }
 
-// SetProfile sets the h264 transcode profile.
+// SetProfile sets the transcode profile. Valid profiles depend on the codec.
 // This can also be passed into Get().
 func (e *Encoder) SetProfile(profile string) string {
-	if e.config.Prof = profile; e.config.Prof != "main" && e.config.Prof != "baseline" && e.config.Prof != "high" {
-		e.config.Prof = DefaultProfile
+	if codec := e.codec(); !slices.Contains(codec.Profiles, profile) {
+		profile = codec.DefaultProfile
 	}
 
+	e.config.Prof = profile
+
 	return e.config.Prof
 }
 
@@ -150,7 +213,7 @@ func (e *Encoder) SetHeight(height string) int {
 	return e.config.Height
 }
 
-// SetCRF sets the h264 transcode CRF value from a string.
+// SetCRF sets the transcode CRF value from a string. The valid range depends on the codec.
 // This can also be passed into Get() as an int.
 func (e *Encoder) SetCRF(crf string) int {
 	e.config.CRF, _ = strconv.Atoi(crf)
@@ -202,7 +265,7 @@ func (e *Encoder) GetVideo(input, title string) (string, io.ReadCloser, error) {
 	var cancel context.CancelFunc
 
 	if e.config.Time > 0 {
-		ctx, cancel = context.WithTimeout(ctx, captureTimeout(e.config.Time))
+		ctx, cancel = context.WithTimeout(ctx, e.config.Limits.captureTimeout(e.config.Time))
 	}
 
 	cmdStr, stream, err := e.GetVideoContext(ctx, input, title)
@@ -236,39 +299,42 @@ func (e *Encoder) GetVideoContext(ctx context.Context, input, title string) (str
 		ctx = context.Background()
 	}
 
-	cmdCtx, cmdCancel := context.WithCancel(ctx)
-	cmdStr, cmd := e.getVideoHandle(cmdCtx, input, "-", title)
-	stderr := newTailBuffer(defaultStderrTail)
-	cmd.Stderr = stderr
-
-	stdoutpipe, err := cmd.StdoutPipe()
-	if err != nil {
-		cmdCancel()
+	return e.streamVideo(ctx, &videoJob{input: input, output: "-", title: title, limit: true})
+}
 
-		return cmdStr, nil, fmt.Errorf("subcommand failed: %w", err)
+// streamVideo runs a video job that writes to stdout, with retries, and returns the stream.
+func (e *Encoder) streamVideo(ctx context.Context, job *videoJob) (string, io.ReadCloser, error) {
+	if err := e.checkFilters(); err != nil {
+		return "", nil, err
 	}
 
-	err = cmd.Start()
-	if err != nil {
-		_ = stdoutpipe.Close()
+	var stream io.ReadCloser
 
-		cmdCancel()
+	cmdStr, err := e.withRetry(ctx, func() (string, error) {
+		cmdCtx, cmdCancel := context.WithCancel(ctx)
+		cmdStr, cmd := e.getVideoHandle(cmdCtx, job)
 
-		return cmdStr, nil, withStderr("run failed", err, stderr.String())
-	}
+		started, err := startStream(cmd, cmdCancel, e.newStderr())
+		if err != nil {
+			return cmdStr, err
+		}
 
-	done := make(chan error, 1)
+		if !e.config.Retry.enabled() {
+			stream = started
 
-	go func() {
-		done <- cmd.Wait()
-	}()
+			return cmdStr, nil
+		}
 
-	return cmdStr, &streamResult{
-		out:       stdoutpipe,
-		done:      done,
-		cmdCancel: cmdCancel,
-		stderr:    stderr,
-	}, nil
+		// Wait for video, so a camera that refuses the connection can be retried.
+		stream, err = peekStream(started)
+
+		return cmdStr, err
+	})
+	if err != nil {
+		return cmdStr, nil, err
+	}
+
+	return cmdStr, stream, nil
 }
 
 // SaveVideo saves a video snippet to a file.
@@ -285,7 +351,7 @@ func (e *Encoder) SaveVideo(input, output, title string) (cmdStr, outputStr stri
 	if e.config.Time > 0 {
 		var cancel func()
 
-		ctx, cancel = context.WithTimeout(ctx, captureTimeout(e.config.Time))
+		ctx, cancel = context.WithTimeout(ctx, e.config.Limits.captureTimeout(e.config.Time))
 		defer cancel()
 	}
 
@@ -309,137 +375,279 @@ func (e *Encoder) SaveVideoContext(
 		return "", "", ErrInvalidOutput
 	}
 
-	cmdStr, cmd := e.getVideoHandle(ctx, input, output, title)
-	stderr := newTailBuffer(defaultStderrTail)
+	if err = e.checkFilters(); err != nil {
+		return "", "", err
+	}
 
-	var stdout bytes.Buffer
+	cmdStr, err = e.withRetry(ctx, func() (string, error) {
+		cmdStr, cmd := e.getVideoHandle(ctx, &videoJob{input: input, output: output, title: title, limit: true})
+		out, err := run(ctx, cmd, e.newStderr())
+		outputStr = e.Redact(string(out))
 
-	cmd.Stdout = &stdout
-	cmd.Stderr = stderr
+		return cmdStr, err
+	})
 
-	err = cmd.Run()
-	if err != nil {
-		return cmdStr, stdout.String(), runError(ctx, "subcommand failed", err, stderr.String())
-	}
+	return cmdStr, outputStr, err
+}
+
+// Args returns the argument list SaveVideo (or GetVideo, if output is "-") runs for an input.
+// The first value is the ffmpeg binary. Use this to log, test or replay a command.
+// Args does not connect to the input, so with AudioCodec auto it shows the transcoded audio options;
+// the capture functions run ffprobe first, and copy the audio instead if the container can hold it.
+func (e *Encoder) Args(input, output, title string) []string {
+	return e.videoArgs(&videoJob{input: input, output: output, title: title, limit: true})
+}
 
-	return cmdStr, stdout.String(), nil
+// Command returns the same command as Args, as a POSIX shell-quoted string that can be pasted into a terminal.
+// Credentials are redacted, like every diagnostic string this library returns; use Args to replay a command.
+func (e *Encoder) Command(input, output, title string) string {
+	return e.Redact(shellJoin(e.Args(input, output, title)))
 }
 
-// fixValues makes sure video request values are sane.
+// fixValues makes sure video request values are sane, and within the encoder's limits.
 func (e *Encoder) fixValues() { //nolint:cyclop // it's a simple switch statement.
+	e.fixTransforms()
+	e.fixScale()
+
+	limits := &e.config.Limits
+
 	switch {
 	case e.config.Height == 0:
-		e.config.Height = DefaultFrameHeight
-	case e.config.Height > MaximumFrameSize:
-		e.config.Height = MaximumFrameSize
-	case e.config.Height < MinimumFrameSize:
-		e.config.Height = MinimumFrameSize
+		e.config.Height = limits.DefaultFrameHeight
+	case e.config.Height > limits.MaximumFrameSize:
+		e.config.Height = limits.MaximumFrameSize
+	case e.config.Height < limits.MinimumFrameSize:
+		e.config.Height = limits.MinimumFrameSize
 	}
 
 	switch {
 	case e.config.Width == 0:
-		e.config.Width = DefaultFrameWidth
-	case e.config.Width > MaximumFrameSize:
-		e.config.Width = MaximumFrameSize
-	case e.config.Width < MinimumFrameSize:
-		e.config.Width = MinimumFrameSize
+		e.config.Width = limits.DefaultFrameWidth
+	case e.config.Width > limits.MaximumFrameSize:
+		e.config.Width = limits.MaximumFrameSize
+	case e.config.Width < limits.MinimumFrameSize:
+		e.config.Width = limits.MinimumFrameSize
 	}
 
-	switch {
+	// yuv420p needs even frame sizes.
+	e.config.Width, e.config.Height = evenDown(e.config.Width), evenDown(e.config.Height)
+
+	switch codec := e.codec(); {
 	case e.config.CRF == 0:
-		e.config.CRF = DefaultEncodeCRF
-	case e.config.CRF < MinimumEncodeCRF:
-		e.config.CRF = MinimumEncodeCRF
-	case e.config.CRF > MaximumEncodeCRF:
-		e.config.CRF = MaximumEncodeCRF
+		e.config.CRF = codec.DefaultCRF
+	case e.config.CRF < codec.MinimumCRF:
+		e.config.CRF = codec.MinimumCRF
+	case e.config.CRF > codec.MaximumCRF:
+		e.config.CRF = codec.MaximumCRF
 	}
 
 	switch {
 	case e.config.Rate == 0:
-		e.config.Rate = DefaultFrameRate
-	case e.config.Rate < MinimumFrameRate:
-		e.config.Rate = MinimumFrameRate
-	case e.config.Rate > MaximumFrameRate:
-		e.config.Rate = MaximumFrameRate
+		e.config.Rate = limits.DefaultFrameRate
+	case e.config.Rate < limits.MinimumFrameRate:
+		e.config.Rate = limits.MinimumFrameRate
+	case e.config.Rate > limits.MaximumFrameRate:
+		e.config.Rate = limits.MaximumFrameRate
 	}
 
+	e.config.AudioBitrate = max(e.config.AudioBitrate, 0)
+	e.config.AudioRate = max(e.config.AudioRate, 0)
+	e.config.AudioChannels = max(e.config.AudioChannels, 0)
+
 	// No minimums.
 	if e.config.Time == 0 {
-		e.config.Time = DefaultCaptureTime
-	} else if e.config.Time > MaximumCaptureTime {
-		e.config.Time = MaximumCaptureTime
+		e.config.Time = limits.DefaultCaptureTime
+	} else if e.config.Time > limits.MaximumCaptureTime {
+		e.config.Time = limits.MaximumCaptureTime
 	}
 
 	if e.config.Size == 0 {
-		e.config.Size = DefaultCaptureSize
-	} else if e.config.Size > MaximumCaptureSize {
-		e.config.Size = MaximumCaptureSize
+		e.config.Size = limits.DefaultCaptureSize
+	} else if e.config.Size > limits.MaximumCaptureSize {
+		e.config.Size = limits.MaximumCaptureSize
 	}
 }
 
 // getVideoHandle is a helper function that creates and returns an ffmpeg command.
 // This is used by higher level function to cobble together an input stream.
-func (e *Encoder) getVideoHandle(ctx context.Context, input, output, title string) (string, *exec.Cmd) {
-	if title == "" {
+func (e *Encoder) getVideoHandle(ctx context.Context, job *videoJob) (string, *exec.Cmd) {
+	withAudio := *job
+	withAudio.audio = e.sourceAudio(ctx, job.input)
+
+	return e.command(ctx, e.videoArgs(&withAudio))
+}
+
+// videoJob describes one video capture built from the encoder config.
+type videoJob struct {
+	input  string
+	output string // file path, or "-" for fragmented MP4 on stdout.
+	tee    string // also save to this file path from the same process. Output must be "-".
+	format string // container for output "-": fragmented mp4 if empty, or mpegts.
+	title  string
+	limit  bool   // apply the capture Time and Size.
+	audio  string // codec of the input's audio, if known. Used by AudioCodec "auto".
+}
+
+// videoArgs returns the ffmpeg arguments to capture video from input to output.
+func (e *Encoder) videoArgs(job *videoJob) []string {
+	input, output, title := job.input, job.output, job.title
+	if title == "" && job.tee != "" {
+		title = filepath.Base(job.tee)
+	} else if title == "" {
 		title = filepath.Base(output)
 	}
 
 	// the order of these values is important.
-	arg := []string{
-		e.config.FFMPEG,
-		"-v", "16", // log level
-		"-i", input,
-		"-metadata", "title=" + title,
+	arg := append(e.inputArgs(input),
+		"-metadata", "title="+title,
 		"-y", "-map", "0",
-	}
+	)
 
-	if isRTSP(input) {
-		arg = append(arg[:3], append([]string{"-rtsp_transport", "tcp"}, arg[3:]...)...)
+	if e.config.Progress != nil {
+		arg = append(arg, "-progress", "pipe:2", "-nostats")
 	}
 
-	if output == "-" {
-		arg = append(arg, "-f", "mp4", "-movflags", "frag_keyframe+empty_moov")
-	} else {
-		arg = append(arg, "-f", "mov")
-	}
+	container := e.container()
 
-	if e.config.Size > 0 {
+	switch {
+	case job.tee != "":
+		arg = append(arg, "-f", "tee")
+	case output == "-" && job.format == FormatMPEGTS:
+		container = FormatMPEGTS
+		arg = append(arg, "-f", container)
+	case output == "-":
+		container = "mp4"
+		arg = append(arg, "-f", container, "-movflags", "frag_keyframe+empty_moov")
+	default:
+		arg = append(arg, "-f", container)
+	}
+
+	if job.limit && e.config.Size > 0 {
 		arg = append(arg, "-fs", strconv.FormatInt(e.config.Size, base10))
 	}
 
-	if e.config.Time > 0 {
+	if job.limit && e.config.Time > 0 {
 		arg = append(arg, "-t", strconv.Itoa(e.config.Time))
 	}
 
+	arg = append(arg, e.codecArgs(container, job.audio)...)
+
+	if job.tee != "" {
+		return append(arg, e.teeArgs(container, job.tee)...)
+	}
+
+	if e.faststart(container) && output != "-" {
+		arg = append(arg, "-movflags", "faststart")
+	}
+
+	return append(arg, output) // save file path goes last.
+}
+
+// faststart returns true if transcoded files in a container should have their index up front.
+func (e *Encoder) faststart(container string) bool {
+	return !e.config.Copy && (container == "mov" || container == "mp4")
+}
+
+// codecArgs returns the video and audio encoding options for a container.
+// Source is the input's audio codec, if known.
+func (e *Encoder) codecArgs(container, source string) []string {
+	var arg []string
+
 	if !e.config.Copy {
-		arg = append(arg, "-vcodec", "libx264",
-			"-profile:v", e.config.Prof,
-			"-level", e.config.Level,
-			"-pix_fmt", "yuv420p",
-			"-s", strconv.Itoa(e.config.Width)+"x"+strconv.Itoa(e.config.Height),
-			"-preset", "superfast",
-			"-crf", strconv.Itoa(e.config.CRF),
-			"-r", strconv.Itoa(e.config.Rate),
-		)
-
-		if output != "-" {
-			arg = append(arg, "-movflags", "faststart")
-		}
+		codec := e.codec()
+		arg = append(arg, codec.videoArgs(e.config, e.sizeArgs())...)
 	} else {
 		arg = append(arg, "-c", "copy")
 	}
 
-	if !e.config.Audio {
-		arg = append(arg, "-an")
-	} else {
-		arg = append(arg, "-c:a", "copy")
+	return append(arg, e.audioArgs(container, source)...)
+}
+
+// container returns the ffmpeg format used for file output.
+// Copied streams go into mov; transcoded streams use the codec's container.
+func (e *Encoder) container() string {
+	if e.config.Copy {
+		return "mov"
+	}
+
+	return e.codec().Container
+}
+
+// inputArgs returns the ffmpeg binary, the log level and the input options.
+// Every command this library builds starts with these values.
+func (e *Encoder) inputArgs(input string) []string {
+	return e.inputArgsLevel(input, "16") // errors only.
+}
+
+// inputArgsLevel returns the same values as inputArgs, with a custom log level.
+func (e *Encoder) inputArgsLevel(input, level string) []string {
+	arg := []string{
+		e.config.FFMPEG,
+		"-v", level,
+	}
+
+	if isRTSP(input) {
+		arg = append(arg, "-rtsp_transport", "tcp")
 	}
 
-	arg = append(arg, output) // save file path goes last.
+	return append(arg, "-i", input)
+}
 
-	// This command string is for diagnostics only; it is not shell-escaped.
-	//nolint:gosec // it's ok, but maybe it's not.
-	return strings.Join(arg, " "), exec.CommandContext(ctx, arg[0], arg[1:]...)
+// command turns an argument list into a diagnostic string and an executable command.
+// The string is POSIX shell-quoted, so it can be pasted into a terminal to reproduce a problem.
+// Credentials are redacted from the string, but not from the command.
+func (e *Encoder) command(ctx context.Context, arg []string) (string, *exec.Cmd) {
+	//nolint:gosec // the binary and arguments come from the library consumer.
+	return e.Redact(shellJoin(arg)), exec.CommandContext(ctx, arg[0], arg[1:]...)
+}
+
+// shellJoin quotes each argument for a POSIX shell, when needed, and joins them with spaces.
+func shellJoin(arg []string) string {
+	quoted := make([]string, len(arg))
+
+	for idx, value := range arg {
+		quoted[idx] = shellQuote(value)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote wraps a value in single quotes if it contains anything a shell may interpret.
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+
+	if strings.IndexFunc(value, needsQuote) < 0 {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func needsQuote(char rune) bool {
+	switch {
+	case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		return false
+	default:
+		return !strings.ContainsRune("_@%+=:,./-", char)
+	}
+}
+
+// run executes a command to completion and returns everything it wrote to stdout.
+// Failures include the tail of stderr.
+func run(ctx context.Context, cmd *exec.Cmd, stderr *tailBuffer) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return stdout.Bytes(), runError(ctx, "subcommand failed", err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
 }
 
 // streamResult is our custom io.ReadCloser that also cleans up the command and context.
@@ -448,10 +656,42 @@ type streamResult struct {
 	done      <-chan error
 	cmdCancel context.CancelFunc
 	stderr    *tailBuffer
+	eof       atomic.Bool
 	closeOnce sync.Once
 	closeErr  error
 }
 
+// startStream starts a command and returns a streamResult to consume its stdout.
+// The cancel function must stop the command; it is called when the stream is closed.
+func startStream(cmd *exec.Cmd, cancel context.CancelFunc, stderr *tailBuffer) (*streamResult, error) {
+	// An io.Pipe (rather than cmd.StdoutPipe) lets Wait return only after every byte is read.
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = stderr
+
+	err := cmd.Start()
+	if err != nil {
+		cancel()
+
+		return nil, withStderr("run failed", err, stderr.String())
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		err := cmd.Wait()
+		_ = writer.Close()
+		done <- err
+	}()
+
+	return &streamResult{
+		out:       reader,
+		done:      done,
+		cmdCancel: cancel,
+		stderr:    stderr,
+	}, nil
+}
+
 func (s *streamResult) Read(data []byte) (int, error) {
 	bytesRead, err := s.out.Read(data)
 	if err == nil {
@@ -459,6 +699,8 @@ func (s *streamResult) Read(data []byte) (int, error) {
 	}
 
 	if errors.Is(err, io.EOF) {
+		s.eof.Store(true)
+
 		return bytesRead, io.EOF
 	}
 
@@ -469,41 +711,113 @@ func (s *streamResult) Read(data []byte) (int, error) {
 	return bytesRead, nil
 }
 
+// Close stops the command if it is still running, and returns its failure, if it had one.
+// Stopping a command because its output was not read to the end is not a failure.
 func (s *streamResult) Close() error {
 	s.closeOnce.Do(func() {
+		defer s.cmdCancel()
+
 		_ = s.out.Close()
 
 		select {
 		case waitErr := <-s.done:
-			if waitErr != nil && !isIgnorableWaitErr(waitErr) {
-				s.closeErr = withStderr("run failed", waitErr, s.stderr.String())
-			}
+			s.closeErr = s.waitError(waitErr)
 
 			return
 		default:
 		}
 
-		s.cmdCancel()
+		// The command closed its output, so it is exiting on its own; collect its status.
+		if s.eof.Load() {
+			s.closeErr = s.waitError(<-s.done)
 
-		waitErr := <-s.done
-		if waitErr != nil && !isIgnorableWaitErr(waitErr) {
-			s.closeErr = withStderr("run failed", waitErr, s.stderr.String())
+			return
 		}
+
+		// The reader went away early. Stop the command, but still report a failure it had on its own.
+		s.cmdCancel()
+		s.closeErr = s.waitError(stoppedEarly(<-s.done))
 	})
 
 	return s.closeErr
 }
 
+// stoppedEarly removes the errors that Close causes when it stops a command before its output
+// is read: the kill signal, and the write to the closed pipe. Other exit statuses are returned.
+func stoppedEarly(err error) error {
+	var exitErr *exec.ExitError
+	if errors.Is(err, io.ErrClosedPipe) || (errors.As(err, &exitErr) && exitErr.ExitCode() == -1) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *streamResult) waitError(err error) error {
+	if err == nil || isIgnorableWaitErr(err) {
+		return nil
+	}
+
+	return withStderr("run failed", err, s.stderr.String())
+}
+
+// tailBuffer keeps the last bytes written to it. If lines is set, every complete line is
+// passed to it first, and lines it returns true for are not kept.
 type tailBuffer struct {
-	buf []byte
-	max int
+	buf     []byte
+	max     int
+	lines   func(line string) bool
+	redact  func(string) string
+	partial []byte
 }
 
 func newTailBuffer(limit int) *tailBuffer {
 	return &tailBuffer{max: limit}
 }
 
+// newStderr returns a tail buffer for ffmpeg's stderr that redacts credentials and reports progress, if configured.
+func (e *Encoder) newStderr() *tailBuffer {
+	stderr := newTailBuffer(defaultStderrTail)
+	stderr.redact = e.Redact
+
+	if e.config.Progress != nil {
+		stderr.lines = newProgressParser(e.config.Progress).line
+	}
+
+	return stderr
+}
+
 func (t *tailBuffer) Write(data []byte) (int, error) {
+	if t.lines == nil {
+		return t.write(data)
+	}
+
+	t.partial = append(t.partial, data...)
+
+	for {
+		idx := bytes.IndexByte(t.partial, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := t.partial[:idx+1]
+		if !t.lines(strings.TrimSpace(string(line))) {
+			_, _ = t.write(line)
+		}
+
+		t.partial = t.partial[idx+1:]
+	}
+
+	// Do not let a line without an end grow forever.
+	if len(t.partial) > t.max {
+		_, _ = t.write(t.partial)
+		t.partial = nil
+	}
+
+	return len(data), nil
+}
+
+func (t *tailBuffer) write(data []byte) (int, error) {
 	if t.max <= 0 {
 		return len(data), nil
 	}
@@ -525,27 +839,31 @@ func (t *tailBuffer) Write(data []byte) (int, error) {
 }
 
 func (t *tailBuffer) String() string {
-	return strings.TrimSpace(string(t.buf))
+	output := strings.TrimSpace(string(t.buf) + string(t.partial))
+	if t.redact != nil {
+		return t.redact(output)
+	}
+
+	return output
 }
 
 func withStderr(prefix string, err error, stderr string) error {
-	if stderr == "" {
-		return fmt.Errorf("%s: %w", prefix, err)
-	}
-
-	return fmt.Errorf("%s: %w: %s", prefix, err, stderr)
+	return newFFmpegError(prefix, err, stderr)
 }
 
 func runError(ctx context.Context, prefix string, err error, stderr string) error {
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-		return withStderr(prefix+": ffmpeg command timed out", err, stderr)
+		ffErr := newFFmpegError(prefix+": ffmpeg command timed out", err, stderr)
+		ffErr.Kind = ErrTimeout
+
+		return ffErr
 	}
 
 	if errors.Is(ctx.Err(), context.Canceled) {
-		return withStderr(prefix+": ffmpeg command canceled", err, stderr)
+		return newFFmpegError(prefix+": ffmpeg command canceled", err, stderr)
 	}
 
-	return withStderr(prefix, err, stderr)
+	return newFFmpegError(prefix, err, stderr)
 }
 
 func isIgnorableWaitErr(err error) bool {
@@ -567,19 +885,6 @@ func isRTSP(input string) bool {
 	return parsedURL.Scheme == "rtsp" || parsedURL.Scheme == "rtsps"
 }
 
-func captureTimeout(seconds int) time.Duration {
-	if seconds <= 0 {
-		return minCommandTimeout
-	}
-
-	const timeoutMultiplier = 6
-
-	timeout := time.Duration(seconds*timeoutMultiplier) * time.Second
-	timeout = max(timeout, minCommandTimeout)
-
-	return timeout
-}
-
 type cancelReadCloser struct {
 	io.ReadCloser
 
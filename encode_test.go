@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,6 +97,28 @@ func TestRTSPTransportOption(t *testing.T) {
 	require.NotContains(t, httpsCmd, "-rtsp_transport tcp")
 }
 
+func TestArgsAndCommand(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "/usr/local/bin/ffmpeg", Copy: true})
+	input := "https://cam.local/video?user=admin&chan=1"
+
+	args := encode.Args(input, "/tmp/my clip.mov", "Tom's Camera")
+	require.Equal(t, []string{
+		"/usr/local/bin/ffmpeg", "-v", "16", "-i", input,
+		"-metadata", "title=Tom's Camera", "-y", "-map", "0", "-f", "mov",
+		"-fs", "2500000", "-t", "15", "-c", "copy", "-an", "/tmp/my clip.mov",
+	}, args)
+	require.Equal(t, "/usr/local/bin/ffmpeg -v 16 -i 'https://cam.local/video?user=admin&chan=1' "+
+		`-metadata 'title=Tom'\''s Camera' -y -map 0 -f mov -fs 2500000 -t 15 -c copy -an '/tmp/my clip.mov'`,
+		encode.Command(input, "/tmp/my clip.mov", "Tom's Camera"))
+
+	cmd, _, err := Get(&Config{FFMPEG: "echo"}).SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-metadata title=out.mov", "safe values must not be quoted")
+	require.Equal(t, "''", shellQuote(""))
+}
+
 func TestSaveVideoErrors(t *testing.T) {
 	t.Parallel()
 
@@ -141,6 +166,49 @@ func TestGetVideoStreamLifecycle(t *testing.T) {
 	require.Contains(t, cmd, "-metadata title=TITLE")
 }
 
+func TestGetVideoStreamClose(t *testing.T) {
+	t.Parallel()
+
+	// Read to the end: the exit status of a failed command is returned by Close.
+	encode := Get(&Config{FFMPEG: fakeFFmpeg(t, "echo partial; exit 3")})
+	_, stream, err := encode.GetVideoContext(context.Background(), "INPUT", "")
+	require.NoError(t, err)
+	_, _ = io.ReadAll(stream)
+
+	var ffErr *FFmpegError
+
+	require.ErrorAs(t, stream.Close(), &ffErr)
+	require.Equal(t, 3, ffErr.ExitCode)
+
+	// Stop reading early after the command failed on its own: the failure is still returned.
+	encode = Get(&Config{FFMPEG: fakeFFmpeg(t, "echo partial; exit 3")})
+	_, stream, err = encode.GetVideoContext(context.Background(), "INPUT", "")
+	require.NoError(t, err)
+	_, err = stream.Read(make([]byte, 1))
+	require.NoError(t, err)
+	time.Sleep(200 * time.Millisecond) // let the command exit.
+	require.ErrorAs(t, stream.Close(), &ffErr)
+	require.Equal(t, 3, ffErr.ExitCode)
+
+	// Stop reading early while the command is still running: stopping it is not a failure.
+	encode = Get(&Config{FFMPEG: fakeFFmpeg(t, "while :; do echo frame; done")})
+	_, stream, err = encode.GetVideoContext(context.Background(), "INPUT", "")
+	require.NoError(t, err)
+	_, err = stream.Read(make([]byte, 1))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+}
+
+// fakeFFmpeg writes a shell script that runs in place of ffmpeg or ffprobe, and returns its path.
+func fakeFFmpeg(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700)) //nolint:gosec // it's a test.
+
+	return path
+}
+
 func TestGetVideoTitleFallbackAndCopy(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,104 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Classified ffmpeg failures. Check for these with errors.Is(), or use errors.As()
+// with *FFmpegError to get the exit code and stderr tail too.
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrStreamNotFound    = errors.New("stream not found")
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrTimeout           = errors.New("timed out")
+	ErrNoSuchFile        = errors.New("no such file or directory")
+	ErrUnknownCodec      = errors.New("unknown codec or encoder")
+	ErrInvalidData       = errors.New("invalid data found in input")
+)
+
+// errorPatterns maps lower-case text found in ffmpeg output to a classified error.
+// The first match wins, so more specific patterns go first.
+//
+//nolint:gochecknoglobals // this is a constant lookup table.
+var errorPatterns = []struct {
+	text string
+	kind error
+}{
+	{"401 unauthorized", ErrUnauthorized},
+	{"403 forbidden", ErrUnauthorized},
+	{"404 not found", ErrStreamNotFound},
+	{"454 session not found", ErrStreamNotFound},
+	{"stream not found", ErrStreamNotFound},
+	{"connection refused", ErrConnectionRefused},
+	{"timed out", ErrTimeout},
+	{"no such file or directory", ErrNoSuchFile},
+	{"executable file not found", ErrNoSuchFile},
+	{"unknown encoder", ErrUnknownCodec},
+	{"unknown decoder", ErrUnknownCodec},
+	{"encoder not found", ErrUnknownCodec},
+	{"decoder not found", ErrUnknownCodec},
+	{"codec not currently supported", ErrUnknownCodec},
+	{"invalid data found when processing input", ErrInvalidData},
+}
+
+// FFmpegError is returned when an ffmpeg (or ffprobe) command fails to start or exits with an error.
+type FFmpegError struct {
+	Kind     error  // One of the classified errors above, or nil if the failure is not recognized.
+	ExitCode int    // Exit code of the command; -1 if it did not exit on its own.
+	Stderr   string // The last bytes the command wrote to stderr.
+	Err      error  // The error from running the command.
+	Command  string // The command that failed, when known. Set on every attempt in a RetryError.
+	prefix   string
+}
+
+// Error returns the failure and the stderr tail.
+func (e *FFmpegError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("%s: %v", e.prefix, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v: %s", e.prefix, e.Err, e.Stderr)
+}
+
+// Unwrap allows errors.Is() to match the classified Kind and the underlying error.
+func (e *FFmpegError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Kind, e.Err}
+}
+
+// newFFmpegError classifies a command failure using its error and stderr.
+func newFFmpegError(prefix string, err error, stderr string) *FFmpegError {
+	ffErr := &FFmpegError{
+		Kind:     classify(stderr + "\n" + err.Error()),
+		ExitCode: -1,
+		Stderr:   stderr,
+		Err:      err,
+		prefix:   prefix,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		ffErr.ExitCode = exitErr.ExitCode()
+	}
+
+	return ffErr
+}
+
+// classify returns the classified error that matches some output, or nil.
+func classify(output string) error {
+	output = strings.ToLower(output)
+
+	for _, pattern := range errorPatterns {
+		if strings.Contains(output, pattern.text) {
+			return pattern.kind
+		}
+	}
+
+	return nil
+}
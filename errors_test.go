@@ -0,0 +1,52 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	asert := assert.New(t)
+
+	for output, kind := range map[string]error{
+		"[rtsp @ 0x55] method DESCRIBE failed: 401 Unauthorized":                                ErrUnauthorized,
+		"[http @ 0x55] HTTP error 403 Forbidden":                                                ErrUnauthorized,
+		"[rtsp @ 0x55] method DESCRIBE failed: 404 Not Found":                                   ErrStreamNotFound,
+		"rtsp://cam/live: Connection refused":                                                   ErrConnectionRefused,
+		"[tcp @ 0x55] Connection to tcp://cam:554 failed: Connection timed out":                 ErrTimeout,
+		"/tmp/missing.mp4: No such file or directory":                                           ErrNoSuchFile,
+		"Unknown encoder 'libx266'":                                                             ErrUnknownCodec,
+		"[mov,mp4 @ 0x55] moov atom not found\nINPUT: Invalid data found when processing input": ErrInvalidData,
+		"everything is fine":                                                                    nil,
+	} {
+		asert.Equal(kind, classify(output), output)
+	}
+}
+
+func TestFFmpegError(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "/path/that/does/not/exist/ffmpeg"})
+	_, _, err := encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrNoSuchFile)
+
+	var ffErr *FFmpegError
+	require.ErrorAs(t, err, &ffErr)
+	require.Equal(t, -1, ffErr.ExitCode)
+	require.Contains(t, err.Error(), "subcommand failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err = runError(ctx, "run failed", errors.New("signal: killed"), "some output") //nolint:err113 // test.
+	require.ErrorIs(t, err, ErrTimeout)
+	require.Equal(t, "run failed: ffmpeg command timed out: signal: killed: some output", err.Error())
+}
@@ -0,0 +1,72 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// videoFilters returns the filters that hide or draw on video: masks, then the overlay.
+// Empty if none are configured.
+func (e *Encoder) videoFilters() []string {
+	// Masks go first, so the overlay text is never hidden.
+	filters := e.maskFilters()
+
+	if e.config.Overlay != nil {
+		filters = append(filters, e.config.Overlay.filter())
+	}
+
+	return filters
+}
+
+// checkFilters returns an error if video filters are configured for a stream that is copied,
+// or if the filters are not valid.
+func (e *Encoder) checkFilters() error {
+	if e.config.Copy && len(e.transformFilters())+len(e.videoFilters()) > 0 {
+		return ErrCopyFilter
+	}
+
+	return e.checkMasks()
+}
+
+// sizeArgs returns the options that scale video to the configured size.
+// When filters are configured, transforms and masks run before scaling, so masks work
+// in source pixels, and the overlay runs after it, so the text size is in output pixels.
+func (e *Encoder) sizeArgs() []string {
+	if len(e.transformFilters())+len(e.videoFilters()) == 0 && e.config.Scale == ScaleStretch {
+		return []string{"-s", strconv.Itoa(e.config.Width) + "x" + strconv.Itoa(e.config.Height)}
+	}
+
+	return []string{"-vf", strings.Join(e.filterChain(), ",")}
+}
+
+// filterChain returns every configured filter, including scaling, in order.
+func (e *Encoder) filterChain() []string {
+	filters := append(e.transformFilters(), e.maskFilters()...)
+	filters = append(filters, e.scaleFilters()...)
+
+	if e.config.Overlay != nil {
+		filters = append(filters, e.config.Overlay.filter())
+	}
+
+	return filters
+}
+
+// filterValue escapes a filter option value for both the option parser and the filtergraph parser.
+func filterValue(value string) string {
+	return backslash(backslash(value, `':`), `'[],;`)
+}
+
+// backslash escapes backslashes and every special character with a backslash.
+func backslash(value, special string) string {
+	var out strings.Builder
+
+	for _, char := range value {
+		if char == '\\' || strings.ContainsRune(special, char) {
+			out.WriteByte('\\')
+		}
+
+		out.WriteRune(char)
+	}
+
+	return out.String()
+}
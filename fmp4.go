@@ -0,0 +1,92 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	boxHeaderSize  = 8
+	largeSizeBytes = 8
+	// Refuse boxes larger than this; a camera fragment is never close.
+	maxBoxSize = 256 << 20
+)
+
+// readFragments splits a fragmented MP4 stream, like the one GetVideoContext produces.
+// Everything before the first moof box (ftyp, moov) is the init segment and is passed to onInit once.
+// Each moof box and the boxes through the following mdat box are one fragment and are passed to onFragment.
+// With frag_keyframe, every fragment starts with a keyframe. Returns nil at the end of the stream.
+func readFragments(reader io.Reader, onInit, onFragment func([]byte)) error {
+	var (
+		pending []byte
+		inited  bool
+		inMoof  bool
+	)
+
+	for {
+		boxType, box, err := readBox(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch {
+		case boxType == "moof":
+			if !inited {
+				onInit(pending)
+				pending, inited = nil, true
+			}
+
+			inMoof = true
+			pending = append(pending, box...)
+		case boxType == "mdat" && inMoof:
+			onFragment(append(pending, box...))
+			pending, inMoof = nil, false
+		case boxType == "mfra" && inited:
+			// The random access index written at the end of the stream is not useful to readers.
+		default:
+			pending = append(pending, box...)
+		}
+	}
+}
+
+// readBox reads one complete box (header included) and returns its four character type.
+// Returns io.EOF only if the stream ends cleanly between boxes.
+func readBox(reader io.Reader) (string, []byte, error) {
+	header := make([]byte, boxHeaderSize, boxHeaderSize+largeSizeBytes)
+
+	_, err := io.ReadFull(reader, header)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", nil, fmt.Errorf("%w: truncated box header", ErrInvalidMP4)
+	} else if err != nil {
+		return "", nil, err //nolint:wrapcheck // io.EOF must not be wrapped.
+	}
+
+	size := uint64(binary.BigEndian.Uint32(header))
+	boxType := string(header[4:boxHeaderSize])
+
+	if size == 1 {
+		header = header[:boxHeaderSize+largeSizeBytes]
+		if _, err = io.ReadFull(reader, header[boxHeaderSize:]); err != nil {
+			return "", nil, fmt.Errorf("%w: truncated %s box size: %w", ErrInvalidMP4, boxType, err)
+		}
+
+		size = binary.BigEndian.Uint64(header[boxHeaderSize:])
+	}
+
+	if size < uint64(len(header)) || size > maxBoxSize {
+		return "", nil, fmt.Errorf("%w: %s box size %d", ErrInvalidMP4, boxType, size)
+	}
+
+	box := make([]byte, size)
+	copy(box, header)
+
+	if _, err = io.ReadFull(reader, box[len(header):]); err != nil {
+		return "", nil, fmt.Errorf("%w: truncated %s box: %w", ErrInvalidMP4, boxType, err)
+	}
+
+	return boxType, box, nil
+}
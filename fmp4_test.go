@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBox returns a box with the given type and payload.
+func testBox(boxType, payload string) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(boxHeaderSize+len(payload)))
+
+	return append(append(box, boxType...), payload...)
+}
+
+// testMP4 returns a fragmented MP4 stream with an init segment and two fragments.
+func testMP4() []byte {
+	var stream []byte
+
+	for _, box := range [][]byte{
+		testBox("ftyp", "isom"),
+		testBox("moov", "tracks"),
+		testBox("moof", "one"),
+		testBox("mdat", "frames1"),
+		testBox("moof", "two"),
+		testBox("mdat", "frames2"),
+		testBox("mfra", "index"),
+	} {
+		stream = append(stream, box...)
+	}
+
+	return stream
+}
+
+func TestReadFragments(t *testing.T) {
+	t.Parallel()
+
+	var (
+		init      []byte
+		fragments [][]byte
+	)
+
+	err := readFragments(bytes.NewReader(testMP4()),
+		func(data []byte) { init = data },
+		func(data []byte) { fragments = append(fragments, data) })
+	require.NoError(t, err)
+	require.Equal(t, append(testBox("ftyp", "isom"), testBox("moov", "tracks")...), init)
+	require.Len(t, fragments, 2)
+	require.Equal(t, append(testBox("moof", "two"), testBox("mdat", "frames2")...), fragments[1])
+}
+
+func TestReadBox(t *testing.T) {
+	t.Parallel()
+
+	// 64-bit box size.
+	large := binary.BigEndian.AppendUint32(nil, 1)
+	large = append(large, "mdat"...)
+	large = binary.BigEndian.AppendUint64(large, boxHeaderSize+largeSizeBytes+3)
+	large = append(large, "abc"...)
+
+	boxType, box, err := readBox(bytes.NewReader(large))
+	require.NoError(t, err)
+	require.Equal(t, "mdat", boxType)
+	require.Equal(t, large, box)
+
+	_, _, err = readBox(bytes.NewReader(testBox("moof", "abc")[:9]))
+	require.ErrorIs(t, err, ErrInvalidMP4)
+
+	_, _, err = readBox(bytes.NewReader([]byte("-v 16 -i INPUT")))
+	require.ErrorIs(t, err, ErrInvalidMP4, "a huge size must not be allocated")
+}
@@ -0,0 +1,206 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health check defaults. Change these if your needs differ.
+//
+//nolint:gochecknoglobals,mnd // these are constants, not variables, but configurable by a consumer.
+var (
+	DefaultHealthDuration = 10 * time.Second // how long HealthCheck watches a stream.
+	MinimumBlackDuration  = time.Second      // shorter black intervals are not reported.
+	MinimumFreezeDuration = 2 * time.Second  // shorter frozen intervals are not reported.
+	MinimumSilentDuration = 2 * time.Second  // shorter silent intervals are not reported.
+)
+
+// HealthReport is what HealthCheck found out about a stream.
+type HealthReport struct {
+	Reachable bool          // ffprobe could open the input.
+	Streams   []ProbeStream // codecs and sizes of every stream in the input.
+	Black     []Interval    // times the picture was black.
+	Frozen    []Interval    // times the picture did not change.
+	Silent    []Interval    // times the audio was silent. Empty if there is no audio.
+	FPS       float64       // frames decoded per second of video.
+	Checked   time.Duration // how much of the stream was watched.
+}
+
+// Interval is a span of time from the start of a health check.
+type Interval struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Healthy returns true if the stream was reachable, produced frames, and was never black or frozen.
+// Silence is not counted; many cameras have quiet microphones, or none at all.
+func (r *HealthReport) Healthy() bool {
+	return r.Reachable && r.FPS > 0 && len(r.Black) == 0 && len(r.Frozen) == 0
+}
+
+// healthParser collects the intervals that the detect filters log.
+type healthParser struct {
+	report  *HealthReport
+	freeze  *time.Duration // start of an open frozen interval.
+	silence *time.Duration // start of an open silent interval.
+}
+
+// HealthCheck watches an input for duration (DefaultHealthDuration if 0) and reports whether the camera
+// is reachable and producing a live picture: black, frozen and silent intervals, and the measured frame rate.
+// A camera that is online but stuck on a frozen or black frame is reachable, but not Healthy().
+// Returns command used for diagnostics, the report and error or nil. If ffprobe cannot
+// open the input, the report says it is not reachable and the ffprobe error is returned.
+// Use the context to add a timeout value (max run duration) to the commands.
+func (e *Encoder) HealthCheck(
+	ctx context.Context, input string, duration time.Duration,
+) (string, *HealthReport, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if duration <= 0 {
+		duration = DefaultHealthDuration
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	report := &HealthReport{}
+
+	cmdStr, probe, err := e.Probe(ctx, input)
+	if err != nil {
+		return cmdStr, report, err
+	}
+
+	report.Reachable, report.Streams = true, probe.Streams
+	parser := &healthParser{report: report}
+	stderr := e.newStderr()
+
+	var last Progress
+
+	progress := newProgressParser(func(update Progress) {
+		last = update
+
+		if e.config.Progress != nil {
+			e.config.Progress(update)
+		}
+	})
+	// The detect filters log at info level; keep their lines out of the error tail.
+	stderr.lines = func(line string) bool { return progress.line(line) || parser.line(line) }
+
+	cmdStr, cmd := e.getHealthHandle(ctx, input, duration, probe.Audio() != nil)
+
+	_, err = run(ctx, cmd, stderr)
+
+	report.Checked = duration
+	if last.OutTime > 0 {
+		report.Checked = last.OutTime
+		report.FPS = float64(last.Frame) / last.OutTime.Seconds()
+	}
+
+	parser.finish(report.Checked)
+
+	return cmdStr, report, err
+}
+
+// getHealthHandle creates and returns an ffmpeg command that decodes an input
+// and logs black, frozen and silent intervals.
+func (e *Encoder) getHealthHandle(
+	ctx context.Context, input string, duration time.Duration, audio bool,
+) (string, *exec.Cmd) {
+	arg := append(e.inputArgsLevel(input, "info"),
+		"-t", seconds(duration),
+		"-progress", "pipe:2", "-nostats",
+		"-vf", "blackdetect=d="+seconds(MinimumBlackDuration)+
+			",freezedetect=d="+seconds(MinimumFreezeDuration),
+	)
+
+	if audio {
+		arg = append(arg, "-af", "silencedetect=d="+seconds(MinimumSilentDuration))
+	} else {
+		arg = append(arg, "-an")
+	}
+
+	arg = append(arg, "-f", "null", "-")
+
+	return e.command(ctx, arg)
+}
+
+// seconds formats a duration the way ffmpeg options expect it.
+func seconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', -1, bits64)
+}
+
+// line consumes a line that a detect filter logged. Returns false for any other line.
+// The filters log lines like these:
+//
+//	[blackdetect @ 0x5581] black_start:0 black_end:2.04 black_duration:2.04
+//	[freezedetect @ 0x5582] lavfi.freezedetect.freeze_start: 3.2
+//	[freezedetect @ 0x5582] lavfi.freezedetect.freeze_end: 6.4
+//	[silencedetect @ 0x5583] silence_start: 1.5
+//	[silencedetect @ 0x5583] silence_end: 3.5 | silence_duration: 2
+func (p *healthParser) line(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "[blackdetect"):
+		start, okStart := logValue(line, "black_start:")
+		end, okEnd := logValue(line, "black_end:")
+
+		if okStart && okEnd {
+			p.report.Black = append(p.report.Black, Interval{Start: start, End: end})
+		}
+	case strings.HasPrefix(line, "[freezedetect"):
+		if start, ok := logValue(line, "freeze_start:"); ok {
+			p.freeze = &start
+		} else if end, ok := logValue(line, "freeze_end:"); ok && p.freeze != nil {
+			p.report.Frozen = append(p.report.Frozen, Interval{Start: *p.freeze, End: end})
+			p.freeze = nil
+		}
+	case strings.HasPrefix(line, "[silencedetect"):
+		if start, ok := logValue(line, "silence_start:"); ok {
+			p.silence = &start
+		} else if end, ok := logValue(line, "silence_end:"); ok && p.silence != nil {
+			p.report.Silent = append(p.report.Silent, Interval{Start: *p.silence, End: end})
+			p.silence = nil
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// finish closes intervals that were still open when the check ended.
+func (p *healthParser) finish(end time.Duration) {
+	if p.freeze != nil {
+		p.report.Frozen = append(p.report.Frozen, Interval{Start: *p.freeze, End: end})
+		p.freeze = nil
+	}
+
+	if p.silence != nil {
+		p.report.Silent = append(p.report.Silent, Interval{Start: *p.silence, End: end})
+		p.silence = nil
+	}
+}
+
+// logValue returns the number of seconds that follows key in a log line.
+func logValue(line, key string) (time.Duration, bool) {
+	_, after, found := strings.Cut(line, key)
+	if !found {
+		return 0, false
+	}
+
+	fields := strings.Fields(after)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	if _, err := strconv.ParseFloat(fields[0], bits64); err != nil {
+		return 0, false
+	}
+
+	return parseSeconds(fields[0]), true
+}
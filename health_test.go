@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthParser(t *testing.T) {
+	t.Parallel()
+
+	report := &HealthReport{Reachable: true, FPS: 5}
+	parser := &healthParser{report: report}
+
+	for _, line := range []string{
+		"[blackdetect @ 0x5581] black_start:0 black_end:2.04 black_duration:2.04",
+		"[freezedetect @ 0x5582] lavfi.freezedetect.freeze_start: 3.2",
+		"[freezedetect @ 0x5582] lavfi.freezedetect.freeze_duration: 3.2",
+		"[freezedetect @ 0x5582] lavfi.freezedetect.freeze_end: 6.4",
+		"[silencedetect @ 0x5583] silence_start: 1.5",
+		"[freezedetect @ 0x5582] lavfi.freezedetect.freeze_start: 8",
+	} {
+		require.True(t, parser.line(line), line)
+	}
+
+	require.False(t, parser.line("Stream #0:0: Video: h264 (Main), yuv420p, 1280x720"))
+	parser.finish(10 * time.Second)
+
+	require.Equal(t, []Interval{{Start: 0, End: 2040 * time.Millisecond}}, report.Black)
+	require.Equal(t, []Interval{
+		{Start: 3200 * time.Millisecond, End: 6400 * time.Millisecond},
+		{Start: 8 * time.Second, End: 10 * time.Second},
+	}, report.Frozen)
+	require.Equal(t, []Interval{{Start: 1500 * time.Millisecond, End: 10 * time.Second}}, report.Silent)
+	require.False(t, report.Healthy())
+	require.True(t, (&HealthReport{Reachable: true, FPS: 5, Silent: report.Silent}).Healthy())
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "ffmpeg", FFProbe: "echo"})
+
+	cmd, report, err := encode.HealthCheck(context.Background(), "rtsp://example.local/stream", 0)
+	require.Error(t, err, "echo does not print JSON")
+	require.Contains(t, cmd, "echo -v 16 -print_format json")
+	require.False(t, report.Reachable)
+	require.False(t, report.Healthy())
+
+	_, health := encode.getHealthHandle(context.Background(), "INPUT", 5*time.Second, true)
+	require.Equal(t, []string{
+		"ffmpeg", "-v", "info", "-i", "INPUT", "-t", "5", "-progress", "pipe:2", "-nostats",
+		"-vf", "blackdetect=d=1,freezedetect=d=2", "-af", "silencedetect=d=2", "-f", "null", "-",
+	}, health.Args)
+
+	_, _, err = encode.HealthCheck(context.Background(), "", time.Second)
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	// The probe finds a video stream, and ffmpeg exits without reporting any problems.
+	encode = Get(&Config{FFMPEG: "echo", FFProbe: fakeFFmpeg(t, `echo '{"streams": [{"codec_type": "video"}]}'`)})
+	_, report, err = encode.HealthCheck(nil, "INPUT", time.Second) //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+	require.True(t, report.Reachable)
+}
@@ -0,0 +1,143 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+)
+
+// Stream formats for Encoder.Handler().
+const (
+	FormatMP4    = "mp4"    // fragmented MP4; plays in browsers with a <video> tag.
+	FormatMPEGTS = "mpegts" // MPEG transport stream; plays in VLC, mpv and HLS tools.
+	FormatMJPEG  = "mjpeg"  // multipart JPEG frames; plays in old browsers and wall displays.
+)
+
+// Handler serves live video from ffmpeg over HTTP. Create one with Encoder.Handler().
+type Handler struct {
+	encoder *Encoder
+	format  string
+	inputs  func(req *http.Request) (string, bool)
+}
+
+// Handler returns an http.Handler that streams live video, in format (mp4, mpegts or mjpeg), for every GET request.
+// Inputs maps a request to an input, like an RTSP URL, and returns false if there is no such camera.
+// Each request runs its own ffmpeg, which stops when the client disconnects.
+// Time and Size from the config do not apply. Failures before any video is sent get a status code
+// from HTTPStatus(); failures after that end the response early.
+func (e *Encoder) Handler(format string, inputs func(req *http.Request) (string, bool)) *Handler {
+	if format != FormatMPEGTS && format != FormatMJPEG {
+		format = FormatMP4
+	}
+
+	return &Handler{encoder: e, format: format, inputs: inputs}
+}
+
+// ServeHTTP streams the video for a request.
+func (h *Handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp.Header().Set("Allow", "GET, HEAD")
+		http.Error(resp, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	input, ok := h.inputs(req)
+	if !ok || input == "" {
+		http.NotFound(resp, req)
+
+		return
+	}
+
+	if req.Method == http.MethodHead {
+		h.headers(resp)
+		resp.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	if h.format == FormatMJPEG {
+		h.serveMJPEG(resp, req, input)
+
+		return
+	}
+
+	// The request context stops ffmpeg when the client goes away.
+	_, stream, err := h.encoder.streamVideo(req.Context(),
+		&videoJob{input: input, output: "-", format: h.format, title: path.Base(req.URL.Path)})
+	if err == nil {
+		// Wait for video, so a failure can still change the status code.
+		stream, err = peekStream(stream)
+	}
+
+	if err != nil {
+		if req.Context().Err() == nil {
+			http.Error(resp, http.StatusText(HTTPStatus(err)), HTTPStatus(err))
+		}
+
+		return
+	}
+	defer stream.Close()
+
+	h.headers(resp)
+	resp.WriteHeader(http.StatusOK)
+
+	_, _ = io.Copy(&flushWriter{writer: resp, flusher: http.NewResponseController(resp)}, stream)
+}
+
+func (h *Handler) headers(resp http.ResponseWriter) {
+	switch h.format {
+	case FormatMPEGTS:
+		resp.Header().Set("Content-Type", "video/mp2t")
+	case FormatMJPEG:
+		resp.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	default:
+		resp.Header().Set("Content-Type", "video/mp4")
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
+// HTTPStatus returns the HTTP status code that best describes an error from this library.
+// Camera failures are 502 Bad Gateway, or 504 Gateway Timeout if the camera timed out.
+// A camera that does not have the requested stream is 404 Not Found.
+// A camera that rejects the configured credentials is 403 Forbidden; the client cannot fix that
+// by logging in, so it is not 401 Unauthorized.
+// Configuration and local problems, like a missing ffmpeg binary, are 500 Internal Server Error.
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrInvalidInput), errors.Is(err, ErrStreamNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrNoSuchFile), errors.Is(err, ErrUnknownCodec),
+		errors.Is(err, ErrCopyFilter), errors.Is(err, ErrInvalidMask):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// flushWriter sends every write to the client right away, rather than when a buffer fills.
+type flushWriter struct {
+	writer  io.Writer
+	flusher *http.ResponseController
+}
+
+func (f *flushWriter) Write(data []byte) (int, error) {
+	size, err := f.writer.Write(data)
+	if err != nil {
+		return size, err //nolint:wrapcheck // the client went away; nobody reads this.
+	}
+
+	_ = f.flusher.Flush()
+
+	return size, nil
+}
@@ -0,0 +1,117 @@
+package ffmpeg
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+)
+
+// Limits are the defaults and bounds that Get() and the Set* methods apply to a Config.
+// Give each Encoder its own Limits in Config.Limits, rather than change the package variables,
+// when parts of a program need different limits. Zero fields use the package Default*, Minimum*
+// and Maximum* values. Get() copies those values, so changing them later does not affect an Encoder.
+type Limits struct {
+	DefaultFrameRate   int
+	MinimumFrameRate   int
+	MaximumFrameRate   int
+	DefaultFrameHeight int
+	DefaultFrameWidth  int
+	MinimumFrameSize   int
+	MaximumFrameSize   int
+	// The EncodeCRF values apply to libx264; other codecs have their own CRF ranges.
+	DefaultEncodeCRF   int
+	MinimumEncodeCRF   int
+	MaximumEncodeCRF   int
+	DefaultCaptureTime int           // seconds.
+	MaximumCaptureTime int           // seconds.
+	DefaultCaptureSize int64         // bytes.
+	MaximumCaptureSize int64         // bytes.
+	DefaultSegmentTime time.Duration // continuous recording file length.
+}
+
+// withDefaults returns the limits with every zero field set from the package variables.
+func (l Limits) withDefaults() Limits {
+	l.DefaultFrameRate = cmp.Or(l.DefaultFrameRate, DefaultFrameRate)
+	l.MinimumFrameRate = cmp.Or(l.MinimumFrameRate, MinimumFrameRate)
+	l.MaximumFrameRate = cmp.Or(l.MaximumFrameRate, MaximumFrameRate)
+	l.DefaultFrameHeight = cmp.Or(l.DefaultFrameHeight, DefaultFrameHeight)
+	l.DefaultFrameWidth = cmp.Or(l.DefaultFrameWidth, DefaultFrameWidth)
+	l.MinimumFrameSize = cmp.Or(l.MinimumFrameSize, MinimumFrameSize)
+	l.MaximumFrameSize = cmp.Or(l.MaximumFrameSize, MaximumFrameSize)
+	l.DefaultEncodeCRF = cmp.Or(l.DefaultEncodeCRF, DefaultEncodeCRF)
+	l.MinimumEncodeCRF = cmp.Or(l.MinimumEncodeCRF, MinimumEncodeCRF)
+	l.MaximumEncodeCRF = cmp.Or(l.MaximumEncodeCRF, MaximumEncodeCRF)
+	l.DefaultCaptureTime = cmp.Or(l.DefaultCaptureTime, DefaultCaptureTime)
+	l.MaximumCaptureTime = cmp.Or(l.MaximumCaptureTime, MaximumCaptureTime)
+	l.DefaultCaptureSize = cmp.Or(l.DefaultCaptureSize, DefaultCaptureSize)
+	l.MaximumCaptureSize = cmp.Or(l.MaximumCaptureSize, MaximumCaptureSize)
+	l.DefaultSegmentTime = cmp.Or(l.DefaultSegmentTime, DefaultSegmentTime)
+
+	return l
+}
+
+// check returns an error for every limit that is negative, and for every minimum,
+// default and maximum that are not in order. The limits must have their defaults.
+func (l *Limits) check() []error {
+	bounds := []struct {
+		name                    string
+		minimum, value, maximum int64
+	}{
+		{"frame rate", int64(l.MinimumFrameRate), int64(l.DefaultFrameRate), int64(l.MaximumFrameRate)},
+		{"frame height", int64(l.MinimumFrameSize), int64(l.DefaultFrameHeight), int64(l.MaximumFrameSize)},
+		{"frame width", int64(l.MinimumFrameSize), int64(l.DefaultFrameWidth), int64(l.MaximumFrameSize)},
+		{"encode crf", int64(l.MinimumEncodeCRF), int64(l.DefaultEncodeCRF), int64(l.MaximumEncodeCRF)},
+		{"capture time", 0, int64(l.DefaultCaptureTime), int64(l.MaximumCaptureTime)},
+		{"capture size", 0, l.DefaultCaptureSize, l.MaximumCaptureSize},
+	}
+
+	var errs []error
+
+	if l.DefaultSegmentTime < 0 {
+		errs = append(errs, fmt.Errorf("%w: segment time limit %v must not be negative",
+			ErrInvalidValue, l.DefaultSegmentTime))
+	}
+
+	for _, bound := range bounds {
+		switch {
+		case bound.minimum < 0 || bound.value < 0 || bound.maximum < 0:
+			errs = append(errs, fmt.Errorf("%w: %s limits %d, %d and %d must not be negative",
+				ErrInvalidValue, bound.name, bound.minimum, bound.value, bound.maximum))
+		case bound.minimum > bound.value || bound.value > bound.maximum:
+			errs = append(errs, fmt.Errorf("%w: %s limits are out of order: minimum %d, default %d, maximum %d",
+				ErrInvalidValue, bound.name, bound.minimum, bound.value, bound.maximum))
+		}
+	}
+
+	return errs
+}
+
+// codec returns the settings for a video encoder, or for DefaultCodec if name is unknown.
+// The libx264 CRF range comes from the limits.
+func (l *Limits) codec(name string) Codec {
+	codec, ok := LookupCodec(name)
+	if !ok {
+		codec, _ = LookupCodec(DefaultCodec)
+	}
+
+	if codec.Name == CodecH264 {
+		codec.DefaultCRF, codec.MinimumCRF, codec.MaximumCRF = l.DefaultEncodeCRF, l.MinimumEncodeCRF, l.MaximumEncodeCRF
+	}
+
+	return codec
+}
+
+// captureTimeout returns how long a capture of seconds may run: six times the clip length, at least
+// minCommandTimeout, because live streams can take longer to process than the clip is long.
+func (l *Limits) captureTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return minCommandTimeout
+	}
+
+	const timeoutMultiplier = 6
+
+	timeout := time.Duration(min(seconds, l.MaximumCaptureTime)*timeoutMultiplier) * time.Second
+	timeout = max(timeout, minCommandTimeout)
+
+	return timeout
+}
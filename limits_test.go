@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsDefaults(t *testing.T) {
+	t.Parallel()
+
+	limits := Get(&Config{}).Config().Limits
+	require.Equal(t, MaximumFrameSize, limits.MaximumFrameSize, "zero limits use the package values")
+	require.Equal(t, MaximumCaptureSize, limits.MaximumCaptureSize)
+	require.Equal(t, DefaultSegmentTime, limits.DefaultSegmentTime)
+
+	encode := Get(&Config{Limits: Limits{MaximumCaptureTime: 30, DefaultFrameRate: 12}})
+	require.Equal(t, 30, encode.Config().Limits.MaximumCaptureTime)
+	require.Equal(t, MinimumFrameRate, encode.Config().Limits.MinimumFrameRate, "unset fields still fall back")
+	require.Equal(t, 12, encode.Config().Rate)
+}
+
+func TestLimitsPerEncoder(t *testing.T) {
+	t.Parallel()
+
+	small := Get(&Config{Limits: Limits{MaximumFrameSize: 640, MaximumCaptureTime: 30, MaximumCaptureSize: 1000}})
+	large := Get(&Config{})
+
+	require.Equal(t, 640, small.SetWidth("1920"))
+	require.Equal(t, 1920, large.SetWidth("1920"), "each encoder has its own limits")
+	require.Equal(t, 30, small.SetTime("600"))
+	require.Equal(t, 600, large.SetTime("600"))
+	require.Equal(t, int64(1000), small.SetSize("5000"))
+
+	_, err := small.SetHeightStrict("720")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = large.SetHeightStrict("720")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, (&Config{Width: 1920, Limits: Limits{MaximumFrameSize: 640}}).Validate(), ErrInvalidValue)
+	require.NoError(t, (&Config{Width: 1920}).Validate())
+}
+
+func TestLimitsCRF(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{Limits: Limits{MinimumEncodeCRF: 20, MaximumEncodeCRF: 25, DefaultEncodeCRF: 22}})
+	require.Equal(t, 22, encode.Config().CRF)
+	require.Equal(t, 25, encode.SetCRF("30"))
+
+	encode.SetCodec(CodecVP9)
+
+	codec, _ := LookupCodec(CodecVP9)
+	require.Equal(t, codec.MaximumCRF, encode.SetCRF("99"), "the encode CRF limits only apply to libx264")
+}
+
+func TestLimitsRecord(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Limits: Limits{DefaultSegmentTime: time.Minute}})
+	_, recorder, err := encode.Record(context.Background(), "INPUT", &Recording{Template: "/tmp/%03d.mp4"})
+	require.NoError(t, err)
+	require.Contains(t, recorder.Command(), "-segment_time 60")
+	require.NoError(t, recorder.Wait())
+}
+
+func TestCaptureTimeout(t *testing.T) {
+	t.Parallel()
+
+	limits := Limits{MaximumCaptureTime: 10}
+	require.Equal(t, minCommandTimeout, limits.captureTimeout(0))
+	require.Equal(t, minCommandTimeout, limits.captureTimeout(1))
+	require.Equal(t, time.Minute, limits.captureTimeout(600), "capped by the maximum capture time")
+}
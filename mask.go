@@ -0,0 +1,207 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Mask modes. Use one of these for Mask.Mode.
+const (
+	MaskBlur     = "blur"
+	MaskPixelate = "pixelate"
+	MaskSolid    = "solid"
+)
+
+// Mask defaults. Change these if your needs differ.
+//
+//nolint:gochecknoglobals,mnd // these are constants, not variables, but configurable by a consumer.
+var (
+	DefaultMaskBlur  = 20 // blur radius in pixels. Smaller masks get a smaller radius.
+	DefaultMaskBlock = 16 // pixelate block size in pixels.
+	DefaultMaskColor = "black"
+)
+
+// Masks smaller than this (in pixels) cannot be blurred. Every mask must be at least this big.
+const minMaskSize = 4
+
+// Mask hides a rectangular region of transcoded video and snapshots, like a neighbor's window.
+// Coordinates are in the source frame, after Crop, Rotate, Transpose and the flips, and before scaling,
+// measured from the top left. So a mask stays on the same part of the picture in every scale mode,
+// and never lands on the bars that fit adds. Pixel masks are checked against the frame when Crop sets
+// its size; otherwise ffmpeg fails if one is outside the frame. Relative masks need no frame size.
+// Masks need transcoding; video functions return ErrCopyFilter if Copy is also true.
+type Mask struct {
+	X        float64
+	Y        float64
+	Width    float64
+	Height   float64
+	Relative bool   // X, Y, Width and Height are fractions (0-1) of the frame, rather than pixels.
+	Mode     string // blur (default), pixelate, solid
+	Color    string // color of a solid mask: black (default), gray, #ff0000 ..
+}
+
+// checkMasks returns an error for the first mask that is too small, or that is not in the frame.
+func (e *Encoder) checkMasks() error {
+	frameWidth, frameHeight := e.maskFrame()
+
+	for idx := range e.config.Masks {
+		mask := &e.config.Masks[idx]
+
+		switch mask.Mode {
+		case "", MaskBlur, MaskPixelate, MaskSolid:
+		default:
+			return fmt.Errorf("%w: mask %d: unknown mode %q", ErrInvalidMask, idx, mask.Mode)
+		}
+
+		if mask.Relative {
+			if mask.X < 0 || mask.Y < 0 || mask.X >= 1 || mask.Y >= 1 || mask.Width <= 0 || mask.Height <= 0 {
+				return fmt.Errorf("%w: mask %d: %gx%g at %g,%g is not a region of the frame",
+					ErrInvalidMask, idx, mask.Width, mask.Height, mask.X, mask.Y)
+			}
+
+			continue
+		}
+
+		x, y, width, height := mask.rect(frameWidth, frameHeight)
+		if x < 0 || y < 0 || width < minMaskSize || height < minMaskSize ||
+			(frameWidth > 0 && (x+width > frameWidth || y+height > frameHeight)) {
+			return fmt.Errorf("%w: mask %d: %dx%d at %d,%d is smaller than %dx%d or outside the frame",
+				ErrInvalidMask, idx, width, height, x, y, minMaskSize, minMaskSize)
+		}
+	}
+
+	return nil
+}
+
+// maskFrame returns the size of the frame masks are applied to: the source after transforms.
+// The size is only known when Crop is set; both are 0 otherwise.
+func (e *Encoder) maskFrame() (int, int) {
+	crop := e.config.Crop
+	if crop.Width <= 0 || crop.Height <= 0 {
+		return 0, 0
+	}
+
+	if e.swapsAxes() {
+		return crop.Height, crop.Width
+	}
+
+	return crop.Width, crop.Height
+}
+
+// maskFilters returns one filter for every mask. Labels are numbered by idx, so they are unique in the graph.
+func (e *Encoder) maskFilters() []string {
+	filters := make([]string, len(e.config.Masks))
+	frameWidth, frameHeight := e.maskFrame()
+
+	for idx := range e.config.Masks {
+		filters[idx] = e.config.Masks[idx].filter(idx, frameWidth, frameHeight)
+	}
+
+	return filters
+}
+
+// rect returns the position and size in pixels of a pixel mask. The edges are moved out to even numbers for
+// chroma subsampling, so the rect always covers the whole mask, but never past the edge of a known frame.
+// A frame size of 0 is unknown.
+func (m *Mask) rect(frameWidth, frameHeight int) (int, int, int, int) {
+	x, width := span(m.X, m.X+m.Width, frameWidth)
+	y, height := span(m.Y, m.Y+m.Height, frameHeight)
+
+	return x, y, width, height
+}
+
+// region returns the mask position and size as filter option values. Pixel masks are numbers from rect.
+// Relative masks are expressions of the frame size, because it is not known until ffmpeg runs. The frame
+// width and height are named by widthVar and heightVar, like iw and ih, or W and H in overlay.
+func (m *Mask) region(frameWidth, frameHeight int, widthVar, heightVar string) (string, string, string, string) {
+	if !m.Relative {
+		x, y, width, height := m.rect(frameWidth, frameHeight)
+
+		return strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(width), strconv.Itoa(height)
+	}
+
+	x, width := relativeSpan(m.X, m.Width, widthVar)
+	y, height := relativeSpan(m.Y, m.Height, heightVar)
+
+	return x, y, width, height
+}
+
+// filter returns the filter that hides the mask region. Blur and pixelate copy the region
+// out of the frame, filter it, and lay it back on top, so the filter starts with a split.
+func (m *Mask) filter(idx, frameWidth, frameHeight int) string {
+	x, y, width, height := m.region(frameWidth, frameHeight, "iw", "ih")
+	left, top, _, _ := m.region(frameWidth, frameHeight, "W", "H")
+	label := "mask" + strconv.Itoa(idx)
+	size := width + ":" + height
+
+	var hide string
+
+	switch {
+	case m.Mode == MaskSolid:
+		return "drawbox=x=" + x + ":y=" + y + ":w=" + width + ":h=" + height +
+			":color=" + filterValue(withDefault(m.Color, DefaultMaskColor)) + ":t=fill"
+	case m.Mode == MaskPixelate && m.Relative:
+		// The region size is not known, so it is scaled up to whole blocks, and laid on a copy of
+		// the region to cut it back to size.
+		block := strconv.Itoa(max(DefaultMaskBlock, 1))
+		hide = "split[" + label + "d][" + label + "e];" +
+			"[" + label + "e]scale=ceil(iw/" + block + "):ceil(ih/" + block + ")," +
+			"scale=iw*" + block + ":ih*" + block + ":flags=neighbor[" + label + "f];" +
+			"[" + label + "d][" + label + "f]overlay"
+	case m.Mode == MaskPixelate:
+		_, _, pixelWidth, pixelHeight := m.rect(frameWidth, frameHeight)
+		block := max(DefaultMaskBlock, 1)
+		hide = "scale=" + strconv.Itoa(max(pixelWidth/block, 1)) + ":" + strconv.Itoa(max(pixelHeight/block, 1)) +
+			",scale=" + size + ":flags=neighbor"
+	case m.Relative:
+		// Chroma planes are half size, and boxblur needs a radius no larger than half a plane.
+		hide = "boxblur=max(min(" + strconv.Itoa(DefaultMaskBlur) + `\,cw/2\,ch/2)\,1)`
+	default:
+		_, _, pixelWidth, pixelHeight := m.rect(frameWidth, frameHeight)
+		hide = "boxblur=" + strconv.Itoa(max(min(DefaultMaskBlur, pixelWidth/minMaskSize, pixelHeight/minMaskSize), 1))
+	}
+
+	return "split[" + label + "a][" + label + "b];" +
+		"[" + label + "b]crop=" + size + ":" + x + ":" + y + "," + hide + "[" + label + "c];" +
+		"[" + label + "a][" + label + "c]overlay=" + left + ":" + top
+}
+
+// relativeSpan returns filter expressions for the even start and the size of a relative mask edge pair,
+// in a frame whose size is named by frameVar. Like span, they round outward, and stop at the frame edge.
+// The commas are escaped for the filtergraph parser.
+func relativeSpan(start, size float64, frameVar string) (string, string) {
+	first, last := "floor("+frameVar+"*"+fraction(start)+"/2)*2", frameVar
+	if start+size < 1 {
+		last = "min(ceil(" + frameVar + "*" + fraction(start+size) + `/2)*2\,` + frameVar + ")"
+	}
+
+	if start <= 0 {
+		return "0", last
+	}
+
+	return first, last + "-" + first
+}
+
+// fraction formats part of a frame, without float error like 0.30000000000000004.
+func fraction(value float64) string {
+	const precision = 1e6
+
+	return strconv.FormatFloat(math.Round(value*precision)/precision, 'f', -1, bits64)
+}
+
+// span returns the even start and the size of the pixels from start to end. The start is rounded down
+// and the end up, so nothing between them is left out. Rounding never moves an end that fits in frame
+// past it. A tiny tolerance keeps float error, like 0.1+0.2 of a frame, from adding two pixels.
+func span(start, end float64, frame int) (int, int) {
+	const tolerance = 1e-9
+
+	first := int(math.Floor(start/2+tolerance)) * 2 //nolint:mnd // two is even.
+	last := int(math.Ceil(end/2-tolerance)) * 2     //nolint:mnd // two is even.
+
+	if end <= float64(frame) {
+		last = min(last, frame)
+	}
+
+	return first, last - first
+}
@@ -0,0 +1,138 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskFilter(t *testing.T) {
+	t.Parallel()
+
+	blur := Mask{X: 100, Y: 51, Width: 200, Height: 100}
+	require.Equal(t, "split[mask0a][mask0b];[mask0b]crop=200:102:100:50,boxblur=20[mask0c];"+
+		"[mask0a][mask0c]overlay=100:50", blur.filter(0, 640, 480))
+
+	pixelate := Mask{X: 320, Y: 240, Width: 320, Height: 240, Mode: MaskPixelate}
+	require.Equal(t, "split[mask1a][mask1b];[mask1b]crop=320:240:320:240,scale=20:15,"+
+		"scale=320:240:flags=neighbor[mask1c];[mask1a][mask1c]overlay=320:240", pixelate.filter(1, 0, 0))
+
+	solid := Mask{Width: 638.5, Height: 480, Mode: MaskSolid}
+	require.Equal(t, "drawbox=x=0:y=0:w=639:h=480:color=black:t=fill", solid.filter(2, 639, 480),
+		"rounding must not push a mask out of a known frame")
+}
+
+func TestMaskFilterRelative(t *testing.T) {
+	t.Parallel()
+
+	// The source frame size is not known, so relative masks are ffmpeg expressions of it.
+	blur := Mask{X: 0.1, Y: 0.1, Width: 0.2, Height: 0.2, Relative: true}
+	require.Equal(t, `split[mask0a][mask0b];[mask0b]crop=min(ceil(iw*0.3/2)*2\,iw)-floor(iw*0.1/2)*2:`+
+		`min(ceil(ih*0.3/2)*2\,ih)-floor(ih*0.1/2)*2:floor(iw*0.1/2)*2:floor(ih*0.1/2)*2,`+
+		`boxblur=max(min(20\,cw/2\,ch/2)\,1)[mask0c];[mask0a][mask0c]overlay=floor(W*0.1/2)*2:floor(H*0.1/2)*2`,
+		blur.filter(0, 0, 0), "float error must not show up in the expressions")
+
+	pixelate := Mask{X: 0.5, Y: 0.5, Width: 0.5, Height: 0.5, Relative: true, Mode: MaskPixelate}
+	require.Equal(t, "split[mask1a][mask1b];[mask1b]crop=iw-floor(iw*0.5/2)*2:ih-floor(ih*0.5/2)*2:"+
+		"floor(iw*0.5/2)*2:floor(ih*0.5/2)*2,split[mask1d][mask1e];"+
+		"[mask1e]scale=ceil(iw/16):ceil(ih/16),scale=iw*16:ih*16:flags=neighbor[mask1f];"+
+		"[mask1d][mask1f]overlay[mask1c];[mask1a][mask1c]overlay=floor(W*0.5/2)*2:floor(H*0.5/2)*2",
+		pixelate.filter(1, 0, 0))
+
+	solid := Mask{Width: 1.5, Height: 0.5, Relative: true, Mode: MaskSolid}
+	require.Equal(t, `drawbox=x=0:y=0:w=iw:h=min(ceil(ih*0.5/2)*2\,ih):color=black:t=fill`, solid.filter(2, 0, 0),
+		"relative masks must stay in the frame")
+}
+
+func TestMaskRectCovers(t *testing.T) {
+	t.Parallel()
+
+	for _, mask := range []Mask{
+		{X: 51, Y: 51, Width: 101, Height: 101},
+		{X: 50, Y: 49, Width: 5, Height: 6},
+		{X: 634.5, Y: 0.5, Width: 5.5, Height: 4},
+	} {
+		x, y, width, height := mask.rect(640, 480)
+
+		require.Zero(t, x%2, mask)
+		require.Zero(t, y%2, mask)
+		require.LessOrEqual(t, float64(x), mask.X, mask)
+		require.LessOrEqual(t, float64(y), mask.Y, mask)
+		require.GreaterOrEqual(t, float64(x+width), mask.X+mask.Width, mask)
+		require.GreaterOrEqual(t, float64(y+height), mask.Y+mask.Height, mask)
+		require.LessOrEqual(t, x+width, 640, mask)
+		require.LessOrEqual(t, y+height, 480, mask)
+	}
+
+	x, y, width, height := (&Mask{X: 51, Y: 51, Width: 101, Height: 101}).rect(0, 0)
+	require.Equal(t, []int{50, 50, 102, 102}, []int{x, y, width, height}, "column and row 51 must be covered")
+}
+
+func TestCheckMasks(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Height: 480, Masks: []Mask{
+		{X: 10, Y: 10, Width: 50, Height: 50, Mode: MaskSolid},
+		{X: 0.1, Y: 0.1, Width: 0.2, Height: 0.2, Relative: true},
+	}})
+	cmd, _, err := encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-vf 'drawbox=x=10:y=10:w=50:h=50:color=black:t=fill,split[mask1a]")
+	require.Contains(t, cmd, "overlay=floor(W*0.1/2)*2:floor(H*0.1/2)*2,scale=640:480'")
+
+	// The frame size is only known from Crop, so pixel masks outside of it are only caught with one.
+	crop := Crop{Width: 640, Height: 480}
+
+	for _, mask := range []Mask{
+		{X: 600, Y: 10, Width: 50, Height: 50},
+		{X: 10, Y: 10, Width: 2, Height: 50},
+		{X: -10, Y: 10, Width: 50, Height: 50},
+		{X: 0.9, Y: 0, Width: 0.2, Height: 0.5, Relative: false},
+		{X: 1, Y: 0, Width: 0.2, Height: 0.5, Relative: true},
+		{X: 0.5, Y: 0.5, Height: 0.5, Relative: true},
+		{X: 10, Y: 10, Width: 50, Height: 50, Mode: "smudge"},
+	} {
+		encode = Get(&Config{FFMPEG: "echo", Crop: crop, Masks: []Mask{mask}})
+		_, _, err = encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+		require.ErrorIs(t, err, ErrInvalidMask, mask)
+		_, _, err = encode.GetSnapshot(context.Background(), "INPUT")
+		require.ErrorIs(t, err, ErrInvalidMask, mask)
+	}
+
+	// Turned sideways, the 640x480 crop is 480 wide.
+	mask := Mask{X: 500, Width: 50, Height: 50}
+	_, _, err = Get(&Config{FFMPEG: "echo", Crop: crop, Masks: []Mask{mask}}).SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	_, _, err = Get(&Config{FFMPEG: "echo", Crop: crop, Rotate: 90, Masks: []Mask{mask}}).
+		SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrInvalidMask)
+
+	encode = Get(&Config{FFMPEG: "echo", Copy: true, Masks: []Mask{{Width: 50, Height: 50}}})
+	_, _, err = encode.GetVideo("INPUT", "")
+	require.ErrorIs(t, err, ErrCopyFilter)
+}
+
+func TestMaskScale(t *testing.T) {
+	t.Parallel()
+
+	// Masks run in the source frame, so with fit, a 16:9 source in 4:3 keeps the
+	// mask on the top left quarter of the picture, rather than on the letterbox bar.
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Height: 480, Scale: ScaleFit, Rotate: 180, Masks: []Mask{
+		{Width: 0.5, Height: 0.5, Relative: true, Mode: MaskSolid},
+	}})
+	cmd, _, err := encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, `-vf 'hflip,vflip,drawbox=x=0:y=0:w=min(ceil(iw*0.5/2)*2\,iw):h=min(ceil(ih*0.5/2)*2\,ih)`+
+		":color=black:t=fill,scale=640:480:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=640:480:-1:-1'")
+
+	// No mode needs to know the output frame size.
+	for _, scale := range []string{ScaleStretch, ScaleFit, ScaleFill, ScaleFitWidth, ScaleFitHeight} {
+		encode := Get(&Config{FFMPEG: "echo", Scale: scale, Masks: []Mask{{Y: 0.5, Width: 1, Height: 0.5, Relative: true}}})
+		_, _, err := encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+		require.NoError(t, err, scale)
+		_, _, err = encode.GetSnapshot(context.Background(), "INPUT")
+		require.NoError(t, err, scale)
+		require.NoError(t, (&Config{Scale: scale, Masks: []Mask{{Y: 700, Width: 100, Height: 20}}}).Validate(), scale)
+	}
+}
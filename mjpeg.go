@@ -0,0 +1,265 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Frames waiting to be received on MJPEG.Frames(). Live video drops frames rather than falling behind.
+	mjpegBuffer = 2
+	// Refuse frames larger than this; a camera frame is never close.
+	maxFrameSize = 32 << 20
+	// mjpegBoundary separates frames in a multipart response.
+	mjpegBoundary = "ffmpegframe"
+)
+
+// MJPEG is a running stream of JPEG frames. Create one with Encoder.GetMJPEG().
+type MJPEG struct {
+	cmdStr string
+	ctx    context.Context //nolint:containedctx // used to tell a canceled stream from a failed one.
+	stream io.ReadCloser
+	closed atomic.Bool
+	frames chan []byte
+	done   chan struct{}
+	err    error
+}
+
+// GetMJPEG starts a long-lived ffmpeg process that turns an input into JPEG frames at the configured Rate.
+// Frame size, transform, mask and overlay settings from the config are honored; Copy does not apply.
+// Time and Size from the config do not apply; the stream runs until it is closed or the context is canceled.
+// Returns command used for diagnostics, the running MJPEG stream and error or nil.
+//
+//nolint:contextcheck // caller-provided context is accepted and used for command execution.
+func (e *Encoder) GetMJPEG(ctx context.Context, input string) (string, *MJPEG, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if err := e.checkMasks(); err != nil {
+		return "", nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdCtx, cmdCancel := context.WithCancel(ctx)
+	cmdStr, cmd := e.getMJPEGHandle(cmdCtx, input)
+
+	stream, err := startStream(cmd, cmdCancel, e.newStderr())
+	if err != nil {
+		return cmdStr, nil, err
+	}
+
+	mjpeg := &MJPEG{
+		cmdStr: cmdStr,
+		ctx:    ctx,
+		stream: stream,
+		frames: make(chan []byte, mjpegBuffer),
+		done:   make(chan struct{}),
+	}
+
+	go mjpeg.run()
+
+	return cmdStr, mjpeg, nil
+}
+
+// Command returns the command used for diagnostics.
+func (m *MJPEG) Command() string {
+	return m.cmdStr
+}
+
+// Frames returns a channel that receives every JPEG frame. The channel is closed when the stream stops.
+// Frames that are not received promptly are dropped, so a slow reader always gets a recent frame.
+func (m *MJPEG) Frames() <-chan []byte {
+	return m.frames
+}
+
+// Wait blocks until the stream stops. Returns nil if it was stopped by Close() or
+// its context, or the ffmpeg failure if the stream ended any other way.
+func (m *MJPEG) Wait() error {
+	<-m.done
+
+	return m.err
+}
+
+// Close stops ffmpeg.
+func (m *MJPEG) Close() error {
+	m.closed.Store(true)
+	err := m.stream.Close()
+	<-m.done
+
+	return err
+}
+
+// run splits the stream into frames until it ends.
+func (m *MJPEG) run() {
+	defer close(m.done)
+	defer close(m.frames)
+
+	reader := bufio.NewReader(m.stream)
+
+	var err error
+
+	for {
+		var frame []byte
+
+		frame, err = readJPEG(reader)
+		if err != nil {
+			break
+		}
+
+		select {
+		case m.frames <- frame:
+		default:
+		}
+	}
+
+	closeErr := m.stream.Close()
+
+	switch {
+	case m.closed.Load() || m.ctx.Err() != nil:
+	case !errors.Is(err, io.EOF):
+		m.err = err
+	default:
+		m.err = closeErr
+	}
+}
+
+// getMJPEGHandle creates and returns an ffmpeg command that writes JPEG frames to stdout.
+func (e *Encoder) getMJPEGHandle(ctx context.Context, input string) (string, *exec.Cmd) {
+	chain := append([]string{"fps=" + strconv.Itoa(e.config.Rate)}, e.filterChain()...)
+	arg := append(e.inputArgs(input),
+		"-an", "-vf", strings.Join(chain, ","),
+		"-c:v", "mjpeg", "-q:v", "5",
+		"-f", "image2pipe", "-",
+	)
+
+	return e.command(ctx, arg)
+}
+
+// readJPEG returns the next frame, from its start of image marker (FF D8) through its
+// end of image marker (FF D9). Anything before the start marker is skipped.
+// Returns io.EOF only if the stream ends cleanly between frames.
+func readJPEG(reader *bufio.Reader) ([]byte, error) {
+	const (
+		marker = 0xFF
+		start  = 0xD8
+		end    = 0xD9
+	)
+
+	for previous := byte(0); ; {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return nil, err //nolint:wrapcheck // io.EOF must not be wrapped.
+		}
+
+		if previous == marker && char == start {
+			break
+		}
+
+		previous = char
+	}
+
+	frame := []byte{marker, start}
+
+	for {
+		chunk, err := reader.ReadSlice(marker)
+		frame = append(frame, chunk...)
+
+		if len(frame) > maxFrameSize {
+			return nil, fmt.Errorf("%w: frame larger than %d bytes", ErrInvalidJPEG, maxFrameSize)
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: truncated frame: %w", ErrInvalidJPEG, err)
+		}
+
+		// Entropy-coded data never contains FF D9, so the first one ends the frame.
+		char, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: truncated frame: %w", ErrInvalidJPEG, err)
+		}
+
+		if char == end {
+			return append(frame, char), nil
+		}
+
+		if char == marker {
+			// This could be the end marker's first byte; read it again.
+			_ = reader.UnreadByte()
+
+			continue
+		}
+
+		frame = append(frame, char)
+	}
+}
+
+// serveMJPEG writes frames as a multipart/x-mixed-replace response until the stream ends or the client goes away.
+func (h *Handler) serveMJPEG(resp http.ResponseWriter, req *http.Request, input string) {
+	_, mjpeg, err := h.encoder.GetMJPEG(req.Context(), input)
+	if err != nil {
+		http.Error(resp, http.StatusText(HTTPStatus(err)), HTTPStatus(err))
+
+		return
+	}
+	defer mjpeg.Close()
+
+	// Wait for a frame, so a failure can still change the status code.
+	frame, ok := <-mjpeg.Frames()
+	if !ok {
+		if err = mjpeg.Wait(); err == nil {
+			err = ErrInvalidJPEG // ffmpeg exited without producing a frame.
+		}
+
+		if req.Context().Err() == nil {
+			http.Error(resp, http.StatusText(HTTPStatus(err)), HTTPStatus(err))
+		}
+
+		return
+	}
+
+	h.headers(resp)
+	resp.WriteHeader(http.StatusOK)
+
+	flusher := http.NewResponseController(resp)
+
+	for ; ok; frame, ok = <-mjpeg.Frames() {
+		if writePart(resp, frame) != nil {
+			return
+		}
+
+		_ = flusher.Flush()
+	}
+}
+
+// writePart writes one frame of a multipart/x-mixed-replace response.
+func writePart(writer io.Writer, frame []byte) error {
+	_, err := fmt.Fprintf(writer, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n",
+		mjpegBoundary, len(frame))
+	if err != nil {
+		return fmt.Errorf("writing frame header: %w", err)
+	}
+
+	if _, err = writer.Write(frame); err != nil {
+		return fmt.Errorf("writing frame: %w", err)
+	}
+
+	if _, err = io.WriteString(writer, "\r\n"); err != nil {
+		return fmt.Errorf("writing frame: %w", err)
+	}
+
+	return nil
+}
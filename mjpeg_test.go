@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadJPEG(t *testing.T) {
+	t.Parallel()
+
+	first := []byte{0xFF, 0xD8, 0x01, 0xFF, 0x00, 0x02, 0xFF, 0xFF, 0xD9}
+	second := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x03, 0xFF, 0xD9}
+	stream := append(append([]byte("noise"), first...), second...)
+	reader := bufio.NewReader(bytes.NewReader(append(stream, 0xFF, 0xD8, 0x04)))
+
+	frame, err := readJPEG(reader)
+	require.NoError(t, err)
+	require.Equal(t, first, frame)
+
+	frame, err = readJPEG(reader)
+	require.NoError(t, err)
+	require.Equal(t, second, frame)
+
+	_, err = readJPEG(reader)
+	require.ErrorIs(t, err, ErrInvalidJPEG, "the last frame is truncated")
+
+	_, err = readJPEG(bufio.NewReader(strings.NewReader("no frames")))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestGetMJPEG(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Height: 480, Rate: 2})
+	cmd, mjpeg, err := encode.GetMJPEG(context.Background(), "rtsp://example.local/stream")
+	require.NoError(t, err)
+	require.Equal(t, cmd, mjpeg.Command())
+	require.Contains(t, cmd, "-an -vf fps=2,scale=640:480 -c:v mjpeg -q:v 5 -f image2pipe -")
+
+	_, open := <-mjpeg.Frames()
+	require.False(t, open, "echo prints no frames")
+	require.NoError(t, mjpeg.Wait())
+	require.NoError(t, mjpeg.Close())
+
+	// The handler needs a frame before it can send a good status.
+	resp := httptest.NewRecorder()
+	encode.Handler(FormatMJPEG, testInputs).ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/cam1", nil))
+	require.Equal(t, http.StatusBadGateway, resp.Code)
+
+	_, _, err = encode.GetMJPEG(context.Background(), "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestWritePart(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, writePart(&buf, []byte("jpeg")))
+	require.Equal(t, "--ffmpegframe\r\nContent-Type: image/jpeg\r\nContent-Length: 4\r\n\r\njpeg\r\n", buf.String())
+}
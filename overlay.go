@@ -0,0 +1,112 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Positions for Overlay text.
+const (
+	TopLeft     = "top-left"
+	TopRight    = "top-right"
+	BottomLeft  = "bottom-left"
+	BottomRight = "bottom-right"
+)
+
+// Overlay defaults. Change these if your needs differ.
+//
+//nolint:gochecknoglobals // these are constants, not variables, but configurable by a consumer.
+var (
+	DefaultOverlayText   = "{camera} {time}"
+	DefaultOverlayTime   = "%Y-%m-%d %H:%M:%S"
+	DefaultOverlaySize   = 24
+	DefaultOverlayColor  = "white"
+	DefaultOverlayBox    = "black@0.5"
+	DefaultOverlayMargin = 10
+)
+
+// Overlay burns text, like a wall-clock timestamp and camera name, into transcoded video.
+// Overlays need transcoding; video functions return ErrCopyFilter if Copy is also true.
+// Empty values use the Default* overlay values.
+type Overlay struct {
+	// Text is drawn as-is, except {camera} is replaced with Camera and
+	// {time} is replaced with the local time when each frame is encoded.
+	Text       string
+	TimeFormat string // strftime format for {time}: %Y-%m-%d %H:%M:%S
+	Camera     string // replaces {camera} in Text.
+	Position   string // top-left (default), top-right, bottom-left, bottom-right
+	FontFile   string // path to a TrueType font. Uses the fontconfig default if empty.
+	FontSize   int    // pixels, in the output frame size.
+	Color      string // ffmpeg color: white, yellow, #ff0000, white@0.8 ..
+	Box        bool   // draw a background box behind the text.
+	BoxColor   string // ffmpeg color of the box: black@0.5 ..
+}
+
+// filter returns the drawtext filter for the overlay.
+func (o *Overlay) filter() string {
+	size := o.FontSize
+	if size <= 0 {
+		size = DefaultOverlaySize
+	}
+
+	var options []string
+
+	if o.FontFile != "" {
+		options = append(options, "fontfile="+filterValue(o.FontFile))
+	}
+
+	options = append(options,
+		"text="+filterValue(o.text()),
+		o.position(),
+		"fontsize="+strconv.Itoa(size),
+		"fontcolor="+filterValue(withDefault(o.Color, DefaultOverlayColor)),
+	)
+
+	if o.Box {
+		options = append(options,
+			"box=1",
+			"boxcolor="+filterValue(withDefault(o.BoxColor, DefaultOverlayBox)),
+			"boxborderw="+strconv.Itoa(DefaultOverlayMargin/2), //nolint:mnd // half the margin.
+		)
+	}
+
+	return "drawtext=" + strings.Join(options, ":")
+}
+
+// text returns the drawtext template: literal text is escaped, and {time} becomes a localtime expansion.
+func (o *Overlay) text() string {
+	format := withDefault(o.TimeFormat, DefaultOverlayTime)
+	parts := strings.Split(withDefault(o.Text, DefaultOverlayText), "{time}")
+
+	// drawtext expands the text once more after the option is parsed: a backslash escapes the next
+	// character and % starts an expansion, so both are escaped, like C:\cam in a camera name.
+	for idx, part := range parts {
+		parts[idx] = backslash(strings.ReplaceAll(part, "{camera}", o.Camera), "%")
+	}
+
+	return strings.Join(parts, "%{localtime:"+backslash(format, ":}'")+"}")
+}
+
+// position returns the x and y options for the overlay position.
+func (o *Overlay) position() string {
+	margin := strconv.Itoa(DefaultOverlayMargin)
+
+	switch o.Position {
+	case TopRight:
+		return "x=w-tw-" + margin + ":y=" + margin
+	case BottomLeft:
+		return "x=" + margin + ":y=h-th-" + margin
+	case BottomRight:
+		return "x=w-tw-" + margin + ":y=h-th-" + margin
+	default:
+		return "x=" + margin + ":y=" + margin
+	}
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlayFilter(t *testing.T) {
+	t.Parallel()
+
+	overlay := &Overlay{
+		Text:     "{camera} 100% {time}",
+		Camera:   "Front: Door",
+		Position: BottomRight,
+		FontFile: "/fonts/My Font.ttf",
+		Box:      true,
+	}
+
+	require.Equal(t, `Front: Door 100\% %{localtime:%Y-%m-%d %H\:%M\:%S}`, overlay.text())
+	require.Equal(t, `drawtext=fontfile=/fonts/My Font.ttf:`+
+		`text=Front\\: Door 100\\\\% %{localtime\\:%Y-%m-%d %H\\\\\\:%M\\\\\\:%S}:`+
+		`x=w-tw-10:y=h-th-10:fontsize=24:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=5`,
+		overlay.filter())
+
+	// A backslash is escaped for drawtext, then for the option, then for the filtergraph:
+	// ffmpeg removes one level each time and draws a single backslash.
+	overlay = &Overlay{Text: `{camera} \ok`, Camera: `C:\cam`}
+	require.Equal(t, `C:\\cam \\ok`, overlay.text())
+	require.Contains(t, overlay.filter(), `text=C\\:\\\\\\\\cam \\\\\\\\ok:`)
+	require.Equal(t, `C:\cam`, unbackslash(unbackslash(unbackslash(`C\\:\\\\\\\\cam`))))
+
+	require.Equal(t, `a\\\'b\\\\c\[d\]\,\;`, filterValue(`a'b\c[d],;`))
+}
+
+func TestOverlayArgs(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Height: 480, Overlay: &Overlay{Camera: "cam1"}})
+	cmd, _, err := encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-pix_fmt yuv420p -vf 'scale=640:480,drawtext=text=cam1 %{localtime")
+	require.NotContains(t, cmd, "-s 640x480", "the filter chain scales the video")
+
+	cmd, _, err = encode.GetSnapshot(context.Background(), "INPUT")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-vf 'scale=640:480,drawtext=", "snapshots get the overlay too")
+
+	encode = Get(&Config{FFMPEG: "echo", Copy: true, Overlay: &Overlay{}})
+	_, _, err = encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrCopyFilter)
+	_, _, err = encode.GetVideo("INPUT", "")
+	require.ErrorIs(t, err, ErrCopyFilter)
+	_, _, err = encode.Record(context.Background(), "INPUT", &Recording{Template: "/tmp/cam-%03d.mov"})
+	require.ErrorIs(t, err, ErrCopyFilter)
+}
+
+// unbackslash removes one level of backslash escaping, like ffmpeg's parsers do.
+func unbackslash(value string) string {
+	var out strings.Builder
+
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] == '\\' && idx+1 < len(value) {
+			idx++
+		}
+
+		out.WriteByte(value[idx])
+	}
+
+	return out.String()
+}
@@ -0,0 +1,128 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Preview formats.
+const (
+	previewGIF  = "gif"
+	previewWebP = "webp"
+)
+
+// DefaultWebPQuality is the quality of animated WebP previews, from 0 to 100.
+//
+//nolint:gochecknoglobals // this is a constant, not a variable, but configurable by a consumer.
+var DefaultWebPQuality = 75
+
+// GetGIF makes a short animated GIF preview from an input and returns it.
+// Time, Rate and the frame size settings from the config are honored; so are masks and overlays.
+// Colors come from a palette made for the clip, so the GIF looks as good as a GIF can.
+// Returns command used for diagnostics, the image and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+func (e *Encoder) GetGIF(ctx context.Context, input string) (string, []byte, error) {
+	return e.getPreview(ctx, input, previewGIF)
+}
+
+// SaveGIF makes a short animated GIF preview from an input and saves it to a file. It will be overwritten.
+// Returns command used for diagnostics, command output and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+//
+//nolint:nonamedreturns // the names help readability.
+func (e *Encoder) SaveGIF(ctx context.Context, input, output string) (cmdStr, outputStr string, err error) {
+	return e.savePreview(ctx, input, output, previewGIF)
+}
+
+// GetWebP makes a short animated WebP preview from an input and returns it.
+// Time, Rate and the frame size settings from the config are honored; so are masks and overlays.
+// Returns command used for diagnostics, the image and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+func (e *Encoder) GetWebP(ctx context.Context, input string) (string, []byte, error) {
+	return e.getPreview(ctx, input, previewWebP)
+}
+
+// SaveWebP makes a short animated WebP preview from an input and saves it to a file. It will be overwritten.
+// Returns command used for diagnostics, command output and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+//
+//nolint:nonamedreturns // the names help readability.
+func (e *Encoder) SaveWebP(ctx context.Context, input, output string) (cmdStr, outputStr string, err error) {
+	return e.savePreview(ctx, input, output, previewWebP)
+}
+
+func (e *Encoder) getPreview(ctx context.Context, input, format string) (string, []byte, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if err := e.checkMasks(); err != nil {
+		return "", nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getPreviewHandle(ctx, input, "-", format)
+	image, err := run(ctx, cmd, e.newStderr())
+
+	return cmdStr, image, err
+}
+
+//nolint:nonamedreturns // the names help readability.
+func (e *Encoder) savePreview(ctx context.Context, input, output, format string) (cmdStr, outputStr string, err error) {
+	if input == "" {
+		return "", "", ErrInvalidInput
+	}
+
+	if output == "" || output == "-" {
+		return "", "", ErrInvalidOutput
+	}
+
+	if err = e.checkMasks(); err != nil {
+		return "", "", err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getPreviewHandle(ctx, input, output, format)
+	out, err := run(ctx, cmd, e.newStderr())
+
+	return cmdStr, e.Redact(string(out)), err
+}
+
+// getPreviewHandle creates and returns an ffmpeg command that writes an animated image to output.
+// Output "-" writes to stdout.
+func (e *Encoder) getPreviewHandle(ctx context.Context, input, output, format string) (string, *exec.Cmd) {
+	// Drop frames first, so the other filters have less to do.
+	chain := append([]string{"fps=" + strconv.Itoa(e.config.Rate)}, e.filterChain()...)
+
+	arg := append(e.inputArgs(input), "-y", "-an")
+
+	if e.config.Time > 0 {
+		arg = append(arg, "-t", strconv.Itoa(e.config.Time))
+	}
+
+	switch format {
+	case previewWebP:
+		arg = append(arg,
+			"-vf", strings.Join(chain, ","),
+			"-c:v", "libwebp", "-q:v", strconv.Itoa(DefaultWebPQuality),
+			"-loop", "0", "-f", "webp",
+		)
+	default:
+		// Make a palette from the clip, then use it to pick colors for every frame.
+		graph := strings.Join(chain, ",") + ",split[preview][palette];" +
+			"[palette]palettegen=stats_mode=diff[colors];[preview][colors]paletteuse=dither=bayer"
+		arg = append(arg, "-vf", graph, "-loop", "0", "-f", "gif")
+	}
+
+	arg = append(arg, output) // save file path goes last.
+
+	return e.command(ctx, arg)
+}
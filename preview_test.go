@@ -0,0 +1,50 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGIF(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 320, Height: 240, Rate: 10, Time: 3})
+
+	cmd, image, err := encode.GetGIF(context.Background(), "rtsp://example.local/stream")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-y -an -t 3 -vf 'fps=10,scale=320:240,split[preview][palette];"+
+		"[palette]palettegen=stats_mode=diff[colors];[preview][colors]paletteuse=dither=bayer' -loop 0 -f gif -")
+	require.Contains(t, string(image), "paletteuse", "echo prints the arguments")
+
+	_, _, err = encode.GetGIF(context.Background(), "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	_, _, err = encode.GetGIF(nil, "INPUT") //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+}
+
+func TestSaveWebP(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Scale: ScaleFitWidth, FlipV: true})
+
+	cmd, _, err := encode.SaveWebP(context.Background(), "INPUT", "/tmp/preview.webp")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-vf 'fps=5,vflip,scale=1280:clip(trunc(ow/a/2)*2\\,100\\,5000)' "+
+		"-c:v libwebp -q:v 75 -loop 0 -f webp /tmp/preview.webp")
+
+	_, _, err = encode.SaveWebP(context.Background(), "INPUT", "-")
+	require.ErrorIs(t, err, ErrInvalidOutput)
+
+	_, _, err = encode.SaveGIF(context.Background(), "INPUT", "/tmp/preview.gif")
+	require.NoError(t, err)
+
+	_, _, err = encode.SaveWebP(nil, "INPUT", "/tmp/preview.webp") //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+
+	encode = Get(&Config{FFMPEG: "echo", Masks: []Mask{{Width: 1}}})
+	_, _, err = encode.GetWebP(context.Background(), "INPUT")
+	require.ErrorIs(t, err, ErrInvalidMask)
+}
@@ -0,0 +1,231 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProbeResult is the typed information ffprobe reports about an input.
+type ProbeResult struct {
+	Format  ProbeFormat
+	Streams []ProbeStream
+}
+
+// ProbeFormat describes the container of a probed input.
+// Duration, Size and BitRate are zero when ffprobe cannot determine them, which is normal for live streams.
+type ProbeFormat struct {
+	Name      string            // rtsp, "mov,mp4,m4a,3gp,3g2,mj2"
+	LongName  string            // RTSP input
+	Streams   int               // number of streams
+	StartTime time.Duration     // first timestamp in the input
+	Duration  time.Duration     // length of files
+	Size      int64             // bytes
+	BitRate   int64             // bits per second
+	Tags      map[string]string // title, encoder, ..
+}
+
+// ProbeStream describes a single audio, video or data stream in a probed input.
+// Video fields are empty for audio streams and vice versa.
+type ProbeStream struct {
+	Index         int               // 0, 1 ..
+	Type          string            // video, audio, data, subtitle
+	Codec         string            // h264, hevc, aac, pcm_mulaw
+	CodecLongName string            // H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10
+	Profile       string            // Main, High, LC
+	Width         int               // 1920
+	Height        int               // 1080
+	PixelFormat   string            // yuv420p
+	FrameRate     float64           // frames per second
+	BitRate       int64             // bits per second
+	Duration      time.Duration     // length of files
+	SampleRate    int               // 8000, 48000
+	Channels      int               // 1, 2
+	ChannelLayout string            // mono, stereo
+	Tags          map[string]string // language, handler_name, ..
+}
+
+// probeOutput is the JSON structure printed by ffprobe. Many numbers are printed as strings.
+type probeOutput struct {
+	Format struct {
+		Name      string            `json:"format_name"`
+		LongName  string            `json:"format_long_name"`
+		Streams   int               `json:"nb_streams"`
+		StartTime string            `json:"start_time"`
+		Duration  string            `json:"duration"`
+		Size      string            `json:"size"`
+		BitRate   string            `json:"bit_rate"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		Type          string            `json:"codec_type"`
+		Codec         string            `json:"codec_name"`
+		CodecLongName string            `json:"codec_long_name"`
+		Profile       string            `json:"profile"`
+		Width         int               `json:"width"`
+		Height        int               `json:"height"`
+		PixelFormat   string            `json:"pix_fmt"`
+		FrameRate     string            `json:"r_frame_rate"`
+		AvgFrameRate  string            `json:"avg_frame_rate"`
+		BitRate       string            `json:"bit_rate"`
+		Duration      string            `json:"duration"`
+		SampleRate    string            `json:"sample_rate"`
+		Channels      int               `json:"channels"`
+		ChannelLayout string            `json:"channel_layout"`
+		Tags          map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+// Probe runs ffprobe against an input and returns what it contains: codecs, resolution, frame rate, audio and more.
+// Returns command used for diagnostics, the probe result and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffprobe command.
+func (e *Encoder) Probe(ctx context.Context, input string) (string, *ProbeResult, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getProbeHandle(ctx, input)
+
+	out, err := run(ctx, cmd, e.newStderr())
+	if err != nil {
+		return cmdStr, nil, err
+	}
+
+	result, err := parseProbe(out)
+
+	return cmdStr, result, err
+}
+
+// Video returns the first video stream, or nil if there is none.
+func (p *ProbeResult) Video() *ProbeStream {
+	return p.stream("video")
+}
+
+// Audio returns the first audio stream, or nil if there is none.
+func (p *ProbeResult) Audio() *ProbeStream {
+	return p.stream("audio")
+}
+
+func (p *ProbeResult) stream(codecType string) *ProbeStream {
+	for idx := range p.Streams {
+		if p.Streams[idx].Type == codecType {
+			return &p.Streams[idx]
+		}
+	}
+
+	return nil
+}
+
+// getProbeHandle creates and returns an ffprobe command that prints JSON to stdout.
+func (e *Encoder) getProbeHandle(ctx context.Context, input string) (string, *exec.Cmd) {
+	arg := []string{
+		e.config.FFProbe,
+		"-v", "16", // log level
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+	}
+
+	if isRTSP(input) {
+		arg = append(arg, "-rtsp_transport", "tcp")
+	}
+
+	arg = append(arg, input) // input goes last.
+
+	return e.command(ctx, arg)
+}
+
+func parseProbe(data []byte) (*ProbeResult, error) {
+	var out probeOutput
+
+	err := json.Unmarshal(data, &out)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ffprobe output: %w", err)
+	}
+
+	result := &ProbeResult{
+		Format: ProbeFormat{
+			Name:      out.Format.Name,
+			LongName:  out.Format.LongName,
+			Streams:   out.Format.Streams,
+			StartTime: parseSeconds(out.Format.StartTime),
+			Duration:  parseSeconds(out.Format.Duration),
+			Size:      parseInt64(out.Format.Size),
+			BitRate:   parseInt64(out.Format.BitRate),
+			Tags:      out.Format.Tags,
+		},
+		Streams: make([]ProbeStream, len(out.Streams)),
+	}
+
+	for idx, stream := range out.Streams {
+		result.Streams[idx] = ProbeStream{
+			Index:         stream.Index,
+			Type:          stream.Type,
+			Codec:         stream.Codec,
+			CodecLongName: stream.CodecLongName,
+			Profile:       stream.Profile,
+			Width:         stream.Width,
+			Height:        stream.Height,
+			PixelFormat:   stream.PixelFormat,
+			FrameRate:     parseRational(stream.AvgFrameRate),
+			BitRate:       parseInt64(stream.BitRate),
+			Duration:      parseSeconds(stream.Duration),
+			SampleRate:    int(parseInt64(stream.SampleRate)),
+			Channels:      stream.Channels,
+			ChannelLayout: stream.ChannelLayout,
+			Tags:          stream.Tags,
+		}
+
+		// Live streams often lack an average; the base rate is the next best thing.
+		if result.Streams[idx].FrameRate == 0 {
+			result.Streams[idx].FrameRate = parseRational(stream.FrameRate)
+		}
+	}
+
+	return result, nil
+}
+
+// parseSeconds turns "12.345000" into a duration. ffprobe prints "N/A" for unknown values.
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, bits64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func parseInt64(value string) int64 {
+	number, _ := strconv.ParseInt(value, base10, bits64)
+
+	return number
+}
+
+// parseRational turns a frame rate like "30000/1001" into a float.
+func parseRational(value string) float64 {
+	num, den, found := strings.Cut(value, "/")
+
+	numerator, err := strconv.ParseFloat(num, bits64)
+	if err != nil {
+		return 0
+	}
+
+	if !found {
+		return numerator
+	}
+
+	denominator, err := strconv.ParseFloat(den, bits64)
+	if err != nil || denominator == 0 {
+		return 0
+	}
+
+	return numerator / denominator
+}
@@ -0,0 +1,106 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProbeJSON = `{
+    "streams": [
+        {
+            "index": 0,
+            "codec_name": "h264",
+            "codec_long_name": "H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10",
+            "profile": "Main",
+            "codec_type": "video",
+            "width": 1920,
+            "height": 1080,
+            "pix_fmt": "yuvj420p",
+            "r_frame_rate": "15/1",
+            "avg_frame_rate": "30000/1001",
+            "bit_rate": "4096000",
+            "duration": "N/A"
+        },
+        {
+            "index": 1,
+            "codec_name": "pcm_mulaw",
+            "codec_type": "audio",
+            "sample_rate": "8000",
+            "channels": 1,
+            "channel_layout": "mono",
+            "r_frame_rate": "0/0",
+            "avg_frame_rate": "0/0"
+        }
+    ],
+    "format": {
+        "format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+        "nb_streams": 2,
+        "start_time": "0.000000",
+        "duration": "12.500000",
+        "size": "1048576",
+        "bit_rate": "671088",
+        "tags": {"title": "Front Door"}
+    }
+}`
+
+func TestParseProbe(t *testing.T) {
+	t.Parallel()
+
+	asert := assert.New(t)
+
+	result, err := parseProbe([]byte(testProbeJSON))
+	require.NoError(t, err)
+	require.Len(t, result.Streams, 2)
+
+	asert.Equal("mov,mp4,m4a,3gp,3g2,mj2", result.Format.Name)
+	asert.Equal(2, result.Format.Streams)
+	asert.Equal(12500*time.Millisecond, result.Format.Duration)
+	asert.Equal(int64(1048576), result.Format.Size)
+	asert.Equal(int64(671088), result.Format.BitRate)
+	asert.Equal("Front Door", result.Format.Tags["title"])
+
+	video := result.Video()
+	require.NotNil(t, video)
+	asert.Equal("h264", video.Codec)
+	asert.Equal(1920, video.Width)
+	asert.Equal(1080, video.Height)
+	asert.InDelta(29.97, video.FrameRate, 0.01)
+	asert.Equal(int64(4096000), video.BitRate)
+	asert.Zero(video.Duration)
+
+	audio := result.Audio()
+	require.NotNil(t, audio)
+	asert.Equal("pcm_mulaw", audio.Codec)
+	asert.Equal(8000, audio.SampleRate)
+	asert.Equal(1, audio.Channels)
+	asert.Zero(audio.FrameRate)
+
+	_, err = parseProbe([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFProbe: "echo"})
+
+	cmd, result, err := encode.Probe(context.Background(), "rtsp://example.local/stream")
+	require.Error(t, err, "echo does not print JSON")
+	require.Nil(t, result)
+	require.Equal(t, "echo -v 16 -print_format json -show_format -show_streams "+
+		"-rtsp_transport tcp rtsp://example.local/stream", cmd)
+
+	_, _, err = encode.Probe(context.Background(), "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	encode = Get(&Config{FFProbe: fakeFFmpeg(t, `echo '{"streams": []}'`)})
+	_, result, err = encode.Probe(nil, "INPUT") //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+	require.Nil(t, result.Video())
+
+	require.Equal(t, DefaultFFprobePath, Get(nil).Config().FFProbe)
+}
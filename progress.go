@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is one progress report from a running ffmpeg command.
+// Values that ffmpeg does not know yet are zero.
+type Progress struct {
+	Frame      int64         // frames written so far
+	FPS        float64       // frames written per second
+	Bitrate    float64       // kilobits per second
+	TotalSize  int64         // bytes written so far
+	OutTime    time.Duration // media time written so far
+	Speed      float64       // 1.0 is real time
+	DupFrames  int64         // frames duplicated to keep the frame rate
+	DropFrames int64         // frames dropped to keep the frame rate
+	Done       bool          // true on the last report
+}
+
+// progressParser collects the key=value lines that ffmpeg -progress prints,
+// and calls report at the end of each block.
+type progressParser struct {
+	report  func(Progress)
+	current Progress
+}
+
+func newProgressParser(report func(Progress)) *progressParser {
+	return &progressParser{report: report}
+}
+
+// line consumes a line of ffmpeg output. Returns false if it is not a progress line.
+func (p *progressParser) line(line string) bool {
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return false
+	}
+
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "frame":
+		p.current.Frame, _ = strconv.ParseInt(value, base10, bits64)
+	case "fps":
+		p.current.FPS, _ = strconv.ParseFloat(value, bits64)
+	case "bitrate":
+		p.current.Bitrate, _ = strconv.ParseFloat(strings.TrimSuffix(value, "kbits/s"), bits64)
+	case "total_size":
+		p.current.TotalSize, _ = strconv.ParseInt(value, base10, bits64)
+	case "out_time_us":
+		micro, _ := strconv.ParseInt(value, base10, bits64)
+		p.current.OutTime = time.Duration(micro) * time.Microsecond
+	case "speed":
+		p.current.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), bits64)
+	case "dup_frames":
+		p.current.DupFrames, _ = strconv.ParseInt(value, base10, bits64)
+	case "drop_frames":
+		p.current.DropFrames, _ = strconv.ParseInt(value, base10, bits64)
+	case "progress":
+		p.current.Done = value == "end"
+		p.report(p.current)
+		p.current = Progress{}
+	case "out_time", "out_time_ms", "stream_0_0_q", "stream_0_1_q":
+		// out_time_us has the same value; the q values are not very useful.
+	default:
+		// ffmpeg adds keys over time, and other stream q values look like this too.
+		return strings.HasPrefix(key, "stream_") && strings.HasSuffix(key, "_q")
+	}
+
+	return true
+}
@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProgress = `[rtsp @ 0x5580] method SETUP failed: 461 Unsupported transport
+frame=25
+fps=12.50
+stream_0_0_q=28.0
+bitrate= 512.3kbits/s
+total_size=65584
+out_time_us=2040000
+out_time_ms=2040000
+out_time=00:00:02.040000
+dup_frames=1
+drop_frames=3
+speed=1.02x
+progress=continue
+frame=50
+fps=N/A
+bitrate=N/A
+total_size=131072
+out_time_us=4080000
+speed=N/A
+progress=end
+`
+
+func TestProgressParser(t *testing.T) {
+	t.Parallel()
+
+	asert := assert.New(t)
+
+	var reports []Progress
+
+	stderr := newTailBuffer(defaultStderrTail)
+	stderr.lines = newProgressParser(func(p Progress) { reports = append(reports, p) }).line
+
+	// Write in odd pieces to make sure lines are put back together.
+	for data := []byte(testProgress); len(data) > 0; {
+		size := min(7, len(data))
+		_, _ = stderr.Write(data[:size])
+		data = data[size:]
+	}
+
+	require.Len(t, reports, 2)
+	asert.Equal(Progress{
+		Frame:      25,
+		FPS:        12.5,
+		Bitrate:    512.3,
+		TotalSize:  65584,
+		OutTime:    2040 * time.Millisecond,
+		Speed:      1.02,
+		DupFrames:  1,
+		DropFrames: 3,
+	}, reports[0])
+	asert.Equal(Progress{Frame: 50, TotalSize: 131072, OutTime: 4080 * time.Millisecond, Done: true}, reports[1])
+	asert.Equal("[rtsp @ 0x5580] method SETUP failed: 461 Unsupported transport", stderr.String(),
+		"only non-progress lines belong in the error tail")
+}
+
+func TestProgressArgs(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo"})
+	cmd, _, err := encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.NotContains(t, cmd, "-progress")
+
+	encode = Get(&Config{FFMPEG: "echo", Progress: func(Progress) {}})
+	cmd, _, err = encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-progress pipe:2 -nostats")
+}
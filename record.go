@@ -0,0 +1,248 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Give ffmpeg this long to finalize the current segment after an interrupt.
+	stopTimeout = 10 * time.Second
+	// Completed segments waiting to be received on Recorder.Segments().
+	segmentBuffer = 10
+)
+
+// Recording defines a continuous recording that is split into fixed-length files.
+type Recording struct {
+	// Template is the file name for each segment. The extension selects the container.
+	// It contains a sequence number pattern like "/data/cam1-%05d.mov", or when
+	// Strftime is true, a wall clock pattern like "/data/cam1-%Y%m%d-%H%M%S.mov".
+	Template string
+	Segment  time.Duration // length of each file. 5 minutes if 0.
+	Strftime bool          // expand Template with strftime, rather than a sequence number.
+	Title    string        // encoded into every segment as the "movie title."
+}
+
+// Segment is a completed file written by a Recorder.
+// Start and End are offsets from the beginning of the recording.
+type Segment struct {
+	Path  string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Recorder is a running continuous recording. Create one with Encoder.Record().
+type Recorder struct {
+	cmdStr   string
+	segments chan Segment
+	done     chan struct{}
+	err      error
+}
+
+// Record starts a long-lived ffmpeg process that records an input into fixed-length files.
+// Time and Size from the config do not apply; the recording runs until the context is canceled.
+// Codec, audio and frame settings from the config are applied to every segment.
+// Returns command used for diagnostics, the running Recorder and error or nil.
+func (e *Encoder) Record(ctx context.Context, input string, rec *Recording) (string, *Recorder, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if rec == nil || rec.Template == "" || rec.Template == "-" {
+		return "", nil, ErrInvalidOutput
+	}
+
+	if err := e.checkFilters(); err != nil {
+		return "", nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getRecordHandle(ctx, input, rec)
+	interruptOnCancel(cmd)
+
+	stderr := e.newStderr()
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return cmdStr, nil, fmt.Errorf("subcommand failed: %w", err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return cmdStr, nil, withStderr("run failed", err, stderr.String())
+	}
+
+	recorder := &Recorder{
+		cmdStr:   cmdStr,
+		segments: make(chan Segment, segmentBuffer),
+		done:     make(chan struct{}),
+	}
+
+	go recorder.watch(ctx, cmd, stdout, stderr, filepath.Dir(rec.Template))
+
+	return cmdStr, recorder, nil
+}
+
+// Command returns the command used for diagnostics.
+func (r *Recorder) Command() string {
+	return r.cmdStr
+}
+
+// Segments returns a channel that receives every completed file.
+// The channel is closed when the recording stops. Receive from it promptly;
+// segments are dropped from the channel (not from disk) if too many are left waiting.
+func (r *Recorder) Segments() <-chan Segment {
+	return r.segments
+}
+
+// Wait blocks until the recording stops. Returns nil if it was stopped by its context,
+// or the ffmpeg failure if the recording ended any other way.
+func (r *Recorder) Wait() error {
+	<-r.done
+
+	return r.err
+}
+
+// watch reports segments as ffmpeg lists them, then collects the exit status.
+func (r *Recorder) watch(ctx context.Context, cmd *exec.Cmd, stdout io.Reader, stderr *tailBuffer, dir string) {
+	defer close(r.done)
+
+	reader := csv.NewReader(stdout)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			continue // not a segment list entry.
+		} else if err != nil {
+			break // io.EOF: ffmpeg exited.
+		}
+
+		segment, ok := parseSegment(record, dir)
+		if !ok {
+			continue
+		}
+
+		// Never block: stdout must be read to the end, so ffmpeg does not stall and can be reaped.
+		select {
+		case r.segments <- segment:
+		default:
+		}
+	}
+
+	close(r.segments)
+
+	err := cmd.Wait()
+	if err != nil && ctx.Err() == nil {
+		r.err = runError(ctx, "run failed", err, stderr.String())
+	}
+}
+
+// getRecordHandle creates and returns an ffmpeg command that uses the segment muxer.
+// The list of completed segments is written to stdout as CSV.
+func (e *Encoder) getRecordHandle(ctx context.Context, input string, rec *Recording) (string, *exec.Cmd) {
+	title := rec.Title
+	if title == "" {
+		title = filepath.Base(rec.Template)
+	}
+
+	segment := rec.Segment
+	if segment < time.Second {
+		segment = e.config.Limits.DefaultSegmentTime
+	}
+
+	arg := append(e.inputArgs(input),
+		"-metadata", "title="+title,
+		"-y", "-map", "0",
+	)
+	arg = append(arg, e.codecArgs(segmentContainer(rec.Template), e.sourceAudio(ctx, input))...)
+	arg = append(arg, "-f", "segment",
+		"-segment_time", strconv.FormatFloat(segment.Seconds(), 'f', -1, bits64),
+		"-reset_timestamps", "1",
+		"-segment_list", "pipe:1",
+		"-segment_list_type", "csv",
+	)
+
+	if rec.Strftime {
+		arg = append(arg, "-strftime", "1")
+	}
+
+	arg = append(arg, rec.Template) // save file path goes last.
+
+	return e.command(ctx, arg)
+}
+
+// segmentContainer returns the format ffmpeg picks for a segment template's extension.
+func segmentContainer(template string) string {
+	switch strings.ToLower(filepath.Ext(template)) {
+	case ".webm":
+		return "webm"
+	case ".mkv":
+		return "matroska"
+	case ".mp4", ".m4v":
+		return "mp4"
+	case ".ts":
+		return "mpegts"
+	default:
+		return "mov"
+	}
+}
+
+// parseSegment turns a segment list entry (file,start,end) into a Segment.
+func parseSegment(record []string, dir string) (Segment, bool) {
+	const fields = 3
+
+	if len(record) != fields || record[0] == "" {
+		return Segment{}, false
+	}
+
+	start, err := strconv.ParseFloat(record[1], bits64)
+	if err != nil {
+		return Segment{}, false
+	}
+
+	end, err := strconv.ParseFloat(record[2], bits64)
+	if err != nil {
+		return Segment{}, false
+	}
+
+	path := record[0]
+	if !filepath.IsAbs(path) {
+		// ffmpeg lists segments relative to the list file; we want them relative to the template.
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+
+	return Segment{
+		Path:  path,
+		Start: time.Duration(start * float64(time.Second)),
+		End:   time.Duration(end * float64(time.Second)),
+	}, true
+}
+
+// interruptOnCancel makes a command stop gracefully when its context is canceled.
+// ffmpeg finalizes its output on interrupt. Windows cannot interrupt, so it is killed there.
+func interruptOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if cmd.Process.Signal(os.Interrupt) != nil {
+			return cmd.Process.Kill()
+		}
+
+		return nil
+	}
+	cmd.WaitDelay = stopTimeout
+}
@@ -0,0 +1,108 @@
+package ffmpeg
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Copy: true})
+	cmd, recorder, err := encode.Record(context.Background(), "rtsp://example.local/stream", &Recording{
+		Template: "/tmp/cam1-%Y%m%d-%H%M%S.mov",
+		Segment:  time.Minute,
+		Strftime: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, cmd, recorder.Command())
+	require.Contains(t, cmd, "-rtsp_transport tcp")
+	require.Contains(t, cmd, "-c copy")
+	require.Contains(t, cmd, "-f segment -segment_time 60 -reset_timestamps 1")
+	require.Contains(t, cmd, "-segment_list pipe:1 -segment_list_type csv -strftime 1 /tmp/cam1-")
+	require.NotContains(t, cmd, "-t ", "recordings must not be limited by capture time")
+	require.NotContains(t, cmd, "-fs ", "recordings must not be limited by capture size")
+
+	for range recorder.Segments() {
+		t.Fatal("echo does not print a segment list")
+	}
+
+	require.NoError(t, recorder.Wait())
+}
+
+func TestRecordUnreadSegments(t *testing.T) {
+	t.Parallel()
+
+	// ffmpeg lists more segments than the channel holds, and exits. Nobody reads Segments().
+	script := `for i in $(seq 1 30); do echo "seg-$i.mov,$i.0,$((i+1)).0"; done`
+	encode := Get(&Config{FFMPEG: fakeFFmpeg(t, script), Copy: true})
+	_, recorder, err := encode.Record(context.Background(), "INPUT", &Recording{Template: "/tmp/seg-%d.mov"})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+
+	go func() { done <- recorder.Wait() }()
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait() blocked because Segments() was not read")
+	}
+
+	segments := 0
+	for range recorder.Segments() {
+		segments++
+	}
+
+	require.Equal(t, segmentBuffer, segments, "the channel keeps the first segments it can hold")
+}
+
+func TestRecordErrors(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo"})
+	_, _, err := encode.Record(context.Background(), "", &Recording{Template: "/tmp/%03d.mov"})
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	_, _, err = encode.Record(context.Background(), "INPUT", &Recording{})
+	require.ErrorIs(t, err, ErrInvalidOutput)
+
+	_, _, err = encode.Record(context.Background(), "INPUT", nil)
+	require.ErrorIs(t, err, ErrInvalidOutput)
+
+	encode = Get(&Config{FFMPEG: "/path/that/does/not/exist/ffmpeg"})
+	_, _, err = encode.Record(context.Background(), "INPUT", &Recording{Template: "/tmp/%03d.mov"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "run failed")
+}
+
+func TestParseSegment(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.FromSlash("/data/cam1")
+
+	segment, ok := parseSegment([]string{"cam1-001.mov", "300.000000", "600.040000"}, dir)
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(dir, "cam1-001.mov"), segment.Path)
+	require.Equal(t, 5*time.Minute, segment.Start)
+	require.Equal(t, 10*time.Minute+40*time.Millisecond, segment.End)
+
+	_, ok = parseSegment([]string{"-v 16 -i INPUT"}, dir)
+	require.False(t, ok)
+
+	_, ok = parseSegment([]string{"cam1-001.mov", "N/A", "1"}, dir)
+	require.False(t, ok)
+}
+
+func TestSegmentContainer(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "webm", segmentContainer("/tmp/a-%03d.webm"))
+	require.Equal(t, "matroska", segmentContainer("/tmp/a-%03d.MKV"))
+	require.Equal(t, "mov", segmentContainer("/tmp/a-%03d.mov"))
+}
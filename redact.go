@@ -0,0 +1,53 @@
+package ffmpeg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redacted replaces hidden values in diagnostic strings.
+const redacted = "xxxxx"
+
+// DefaultRedactParams are the URL query parameters hidden in command strings and errors
+// when Config.RedactParams is nil.
+//
+//nolint:gochecknoglobals // this is a constant, not a variable, but configurable by a consumer.
+var DefaultRedactParams = []string{"token", "password", "pass", "pwd", "auth", "key"}
+
+// userInfo matches the user:pass@ part of any URL. It runs to the last @ in the authority,
+// because passwords may contain an unescaped @.
+var userInfo = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s?#']+@`)
+
+// Redact hides credentials in a string: URL user info (user:pass@) and the values of the
+// configured query parameters. Every command string, command output and error tail this
+// library returns is passed through Redact; ffmpeg still receives the real values.
+func (e *Encoder) Redact(value string) string {
+	value = userInfo.ReplaceAllString(value, "${1}"+redacted+"@")
+
+	if e.params == nil {
+		return value
+	}
+
+	return e.params.ReplaceAllString(value, "${1}"+redacted)
+}
+
+// redactParams returns a regular expression that matches the values of query parameters.
+func redactParams(params []string) *regexp.Regexp {
+	if params == nil {
+		params = DefaultRedactParams
+	}
+
+	quoted := make([]string, 0, len(params))
+
+	for _, param := range params {
+		if param != "" {
+			quoted = append(quoted, regexp.QuoteMeta(param))
+		}
+	}
+
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	return regexp.MustCompile(`(?i)([?&;](?:` + strings.Join(quoted, "|") + `)=)[^&;\s'"#]*`)
+}
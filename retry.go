@@ -0,0 +1,170 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Default values for a RetryPolicy. Change these if your needs differ.
+//
+//nolint:gochecknoglobals // these are constants, not variables, but configurable by a consumer.
+var (
+	DefaultRetryBackoff    = time.Second
+	DefaultRetryMaxBackoff = 30 * time.Second
+	// DefaultRetryable are the classified errors retried when RetryPolicy.Retryable is empty.
+	DefaultRetryable = []error{ErrConnectionRefused, ErrTimeout, ErrInvalidData}
+)
+
+// RetryPolicy controls how failed commands are retried. The zero value does not retry.
+// A command is only retried if it failed with one of the Retryable classified errors
+// and the context is not done.
+type RetryPolicy struct {
+	Attempts   int           // total attempts, including the first. 0 or 1 disables retries.
+	Backoff    time.Duration // wait before the second attempt, doubled for each attempt after. 1s if 0.
+	MaxBackoff time.Duration // longest wait between attempts. 30s if 0.
+	Jitter     float64       // random fraction of each wait to add or remove, 0.0 to 1.0.
+	Retryable  []error       // classified errors to retry, like ErrConnectionRefused. DefaultRetryable if empty.
+}
+
+// RetryError is returned when more than one attempt was made and none succeeded.
+// It wraps the last attempt's error, so errors.Is() and errors.As() work as usual.
+type RetryError struct {
+	Attempts []*FFmpegError // every attempt, in order. Each contains its Command and Stderr.
+}
+
+// Error lists the failure of every attempt.
+func (r *RetryError) Error() string {
+	msgs := make([]string, len(r.Attempts))
+	for idx, attempt := range r.Attempts {
+		msgs[idx] = fmt.Sprintf("attempt %d: %v", idx+1, attempt)
+	}
+
+	return fmt.Sprintf("%d attempts failed: %s", len(r.Attempts), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the last attempt's error.
+func (r *RetryError) Unwrap() error {
+	return r.Attempts[len(r.Attempts)-1]
+}
+
+func (r *RetryPolicy) enabled() bool {
+	return r.Attempts > 1
+}
+
+// retryable returns true if an error is one that should be retried.
+func (r *RetryPolicy) retryable(err error) bool {
+	kinds := r.Retryable
+	if len(kinds) == 0 {
+		kinds = DefaultRetryable
+	}
+
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns how long to wait after a number of failed attempts.
+func (r *RetryPolicy) backoff(failures int) time.Duration {
+	wait, limit := r.Backoff, r.MaxBackoff
+	if wait <= 0 {
+		wait = DefaultRetryBackoff
+	}
+
+	if limit <= 0 {
+		limit = DefaultRetryMaxBackoff
+	}
+
+	for range failures - 1 {
+		if wait *= 2; wait >= limit {
+			break
+		}
+	}
+
+	wait = min(wait, limit)
+
+	if jitter := min(max(r.Jitter, 0), 1); jitter > 0 {
+		wait += time.Duration(float64(wait) * jitter * (rand.Float64()*2 - 1)) //nolint:gosec // not for crypto.
+	}
+
+	return wait
+}
+
+// withRetry calls attempt until it succeeds, fails with an error that is not retryable,
+// the context is done, or the policy runs out of attempts. Returns the last command string.
+func (e *Encoder) withRetry(ctx context.Context, attempt func() (string, error)) (string, error) {
+	policy := e.config.Retry
+
+	cmdStr, err := attempt()
+	if err == nil || !policy.enabled() || !policy.retryable(err) || ctx.Err() != nil {
+		return cmdStr, err
+	}
+
+	retryErr := &RetryError{}
+
+	for failures := 1; ; failures++ {
+		retryErr.Attempts = append(retryErr.Attempts, attemptError(cmdStr, err))
+
+		if failures >= policy.Attempts || !policy.retryable(err) || ctx.Err() != nil {
+			return cmdStr, retryErr
+		}
+
+		timer := time.NewTimer(policy.backoff(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return cmdStr, retryErr
+		case <-timer.C:
+		}
+
+		if cmdStr, err = attempt(); err == nil {
+			return cmdStr, nil
+		}
+	}
+}
+
+// attemptError records the command string with a failed attempt's error.
+func attemptError(cmdStr string, err error) *FFmpegError {
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) {
+		ffErr = newFFmpegError("run failed", err, "")
+	}
+
+	ffErr.Command = cmdStr
+
+	return ffErr
+}
+
+// peekedStream is a stream that has already produced its first byte.
+type peekedStream struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// peekStream waits for a stream to produce data. If the command exits without producing
+// any, the stream is closed and its failure is returned.
+func peekStream(stream io.ReadCloser) (io.ReadCloser, error) {
+	reader := bufio.NewReader(stream)
+
+	if _, err := reader.Peek(1); err != nil {
+		if closeErr := stream.Close(); closeErr != nil {
+			return nil, closeErr
+		}
+
+		if !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+
+	return &peekedStream{Reader: reader, Closer: stream}, nil
+}
@@ -0,0 +1,72 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrySaveVideo(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{
+		FFMPEG: "/path/that/does/not/exist/ffmpeg",
+		Retry: RetryPolicy{
+			Attempts:  3,
+			Backoff:   time.Millisecond,
+			Retryable: []error{ErrNoSuchFile},
+		},
+	})
+
+	cmd, _, err := encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrNoSuchFile)
+
+	var retryErr *RetryError
+	require.ErrorAs(t, err, &retryErr)
+	require.Len(t, retryErr.Attempts, 3)
+
+	for _, attempt := range retryErr.Attempts {
+		require.Equal(t, cmd, attempt.Command)
+	}
+
+	require.Contains(t, err.Error(), "3 attempts failed: attempt 1: subcommand failed")
+
+	// Not retryable: the error comes back as-is.
+	encode.config.Retry.Retryable = []error{ErrUnauthorized}
+	_, _, err = encode.SaveVideoContext(context.Background(), "INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrNoSuchFile)
+	require.NotErrorAs(t, err, &retryErr)
+}
+
+func TestRetryGetVideo(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Retry: RetryPolicy{Attempts: 2}})
+
+	_, stream, err := encode.GetVideoContext(context.Background(), "INPUT", "TITLE")
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "-metadata title=TITLE", "peeked data must not be lost")
+	require.NoError(t, stream.Close())
+}
+
+func TestRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, policy.backoff(1))
+	require.Equal(t, 2*time.Second, policy.backoff(2))
+	require.Equal(t, 4*time.Second, policy.backoff(3))
+	require.Equal(t, 5*time.Second, policy.backoff(4))
+	require.Equal(t, 5*time.Second, policy.backoff(100))
+
+	policy.Jitter = 0.5
+	for range 100 {
+		require.InDelta(t, 2*time.Second, policy.backoff(2), float64(time.Second))
+	}
+}
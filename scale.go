@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Scale modes. Use one of these for Config.Scale.
+const (
+	ScaleStretch   = "stretch"    // scale to Width x Height, ignoring the aspect ratio.
+	ScaleFit       = "fit"        // fit inside Width x Height, and pad the rest with black bars.
+	ScaleFill      = "fill"       // fill Width x Height, and crop what does not fit.
+	ScaleFitWidth  = "fit-width"  // scale to Width; the height follows the aspect ratio.
+	ScaleFitHeight = "fit-height" // scale to Height; the width follows the aspect ratio.
+)
+
+// SetScale sets how video is scaled to Width x Height. Unknown values are replaced with stretch.
+// This can also be passed into Get().
+func (e *Encoder) SetScale(mode string) string {
+	e.config.Scale = mode
+	e.fixValues()
+
+	return e.config.Scale
+}
+
+// fixScale makes sure the scale mode is known.
+func (e *Encoder) fixScale() {
+	if !slices.Contains([]string{ScaleFit, ScaleFill, ScaleFitWidth, ScaleFitHeight}, e.config.Scale) {
+		e.config.Scale = ScaleStretch
+	}
+}
+
+// scaleFilters returns the filters that scale video to the configured size in the configured mode.
+// Sizes that follow the aspect ratio are rounded to even numbers and kept within the frame size limits.
+func (e *Encoder) scaleFilters() []string {
+	width, height := strconv.Itoa(e.config.Width), strconv.Itoa(e.config.Height)
+	// The commas are escaped for the filtergraph parser.
+	limits := `\,` + strconv.Itoa(evenDown(e.config.Limits.MinimumFrameSize+1)) +
+		`\,` + strconv.Itoa(evenDown(e.config.Limits.MaximumFrameSize)) + ")"
+
+	switch e.config.Scale {
+	case ScaleFit:
+		return []string{
+			"scale=" + width + ":" + height + ":force_original_aspect_ratio=decrease:force_divisible_by=2",
+			"pad=" + width + ":" + height + ":-1:-1",
+		}
+	case ScaleFill:
+		return []string{
+			"scale=" + width + ":" + height + ":force_original_aspect_ratio=increase:force_divisible_by=2",
+			"crop=" + width + ":" + height,
+		}
+	case ScaleFitWidth:
+		return []string{"scale=" + width + ":clip(trunc(ow/a/2)*2" + limits}
+	case ScaleFitHeight:
+		return []string{"scale=clip(trunc(oh*a/2)*2" + limits + ":" + height}
+	default:
+		return []string{"scale=" + width + ":" + height}
+	}
+}
+
+// evenDown rounds a pixel value down to an even number; yuv420p needs even frame sizes.
+func evenDown(value int) int {
+	return value - value%2 //nolint:mnd // two is even.
+}
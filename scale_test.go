@@ -0,0 +1,39 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleFilters(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 641, Height: 481})
+	require.Equal(t, ScaleStretch, encode.Config().Scale)
+	require.Equal(t, 640, encode.Config().Width, "sizes are even")
+	require.Equal(t, 480, encode.Config().Height, "sizes are even")
+	require.Contains(t, encode.Command("INPUT", "-", ""), "-s 640x480", "stretch without filters keeps -s")
+
+	require.Equal(t, ScaleFit, encode.SetScale(ScaleFit))
+	require.Equal(t, []string{
+		"scale=640:480:force_original_aspect_ratio=decrease:force_divisible_by=2",
+		"pad=640:480:-1:-1",
+	}, encode.scaleFilters())
+	require.Contains(t, encode.Command("INPUT", "-", ""), "-vf scale=640:480:force_original_aspect_ratio=decrease")
+
+	encode.SetScale(ScaleFill)
+	require.Equal(t, []string{
+		"scale=640:480:force_original_aspect_ratio=increase:force_divisible_by=2",
+		"crop=640:480",
+	}, encode.scaleFilters())
+
+	encode.SetScale(ScaleFitWidth)
+	require.Equal(t, []string{`scale=640:clip(trunc(ow/a/2)*2\,100\,5000)`}, encode.scaleFilters())
+
+	encode.SetScale(ScaleFitHeight)
+	require.Equal(t, []string{`scale=clip(trunc(oh*a/2)*2\,100\,5000):480`}, encode.scaleFilters())
+
+	require.Equal(t, ScaleStretch, encode.SetScale("squish"))
+	require.Equal(t, []string{"scale=640:480"}, encode.scaleFilters())
+}
@@ -0,0 +1,98 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Image formats for Encoder.GetSnapshotFormat().
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// GetSnapshot grabs a single still frame from an input and returns it as JPEG bytes.
+// Input may be an RTSP URL or anything else ffmpeg can read. Width and Height from the config are honored.
+// Returns command used for diagnostics, the image and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+func (e *Encoder) GetSnapshot(ctx context.Context, input string) (string, []byte, error) {
+	return e.GetSnapshotFormat(ctx, input, FormatJPEG)
+}
+
+// GetSnapshotFormat is GetSnapshot with a choice of image format: jpeg or png. Unknown formats are jpeg.
+func (e *Encoder) GetSnapshotFormat(ctx context.Context, input, format string) (string, []byte, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if err := e.checkMasks(); err != nil {
+		return "", nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getSnapshotHandle(ctx, input, "-", format == FormatPNG)
+	image, err := run(ctx, cmd, e.newStderr())
+
+	return cmdStr, image, err
+}
+
+// SaveSnapshot grabs a single still frame from an input and saves it to a file.
+// The image is saved as PNG if output ends with .png, otherwise as JPEG. It will be overwritten.
+// Returns command used for diagnostics, command output and error or nil.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+//
+//nolint:nonamedreturns // the names help readability.
+func (e *Encoder) SaveSnapshot(ctx context.Context, input, output string) (cmdStr, outputStr string, err error) {
+	if input == "" {
+		return "", "", ErrInvalidInput
+	}
+
+	if output == "" || output == "-" {
+		return "", "", ErrInvalidOutput
+	}
+
+	if err = e.checkMasks(); err != nil {
+		return "", "", err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmdStr, cmd := e.getSnapshotHandle(ctx, input, output, strings.EqualFold(filepath.Ext(output), ".png"))
+	out, err := run(ctx, cmd, e.newStderr())
+
+	return cmdStr, e.Redact(string(out)), err
+}
+
+// getSnapshotHandle creates and returns an ffmpeg command that writes one frame, as PNG or JPEG, to output.
+// Output "-" writes the image to stdout.
+func (e *Encoder) getSnapshotHandle(ctx context.Context, input, output string, png bool) (string, *exec.Cmd) {
+	arg := append(e.inputArgs(input),
+		"-y", "-an",
+		"-frames:v", "1",
+	)
+	arg = append(arg, e.sizeArgs()...)
+
+	if output == "-" {
+		arg = append(arg, "-f", "image2pipe")
+	} else {
+		// Update writes one file, so a % in the path is not read as a numbered sequence pattern.
+		arg = append(arg, "-f", "image2", "-update", "1")
+	}
+
+	if png {
+		arg = append(arg, "-c:v", "png")
+	} else {
+		arg = append(arg, "-c:v", "mjpeg", "-q:v", "2")
+	}
+
+	arg = append(arg, output) // save file path goes last.
+
+	return e.command(ctx, arg)
+}
@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSnapshot(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo", Width: 640, Height: 480})
+
+	cmd, image, err := encode.GetSnapshot(context.Background(), "rtsp://example.local/stream")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-rtsp_transport tcp")
+	require.Contains(t, cmd, "-frames:v 1")
+	require.Contains(t, cmd, "-s 640x480")
+	require.Contains(t, cmd, "-f image2pipe -c:v mjpeg")
+	require.True(t, strings.HasSuffix(cmd, " -"), "snapshot should be written to stdout")
+	require.Equal(t, cmd, "echo "+strings.TrimSpace(string(image)))
+
+	_, _, err = encode.GetSnapshot(context.Background(), "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	cmd, _, err = encode.GetSnapshotFormat(context.Background(), "INPUT", FormatPNG)
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f image2pipe -c:v png")
+	require.NotContains(t, cmd, "mjpeg")
+
+	cmd, _, err = encode.GetSnapshotFormat(context.Background(), "INPUT", "gif")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f image2pipe -c:v mjpeg", "unknown formats are jpeg")
+
+	_, _, err = encode.GetSnapshot(nil, "INPUT") //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo"})
+
+	cmd, _, err := encode.SaveSnapshot(context.Background(), "INPUT", "/tmp/snap.PNG")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f image2 -update 1 -c:v png")
+	require.NotContains(t, cmd, "-rtsp_transport tcp")
+
+	cmd, _, err = encode.SaveSnapshot(context.Background(), "INPUT", "/tmp/snap.jpg")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-f image2 -update 1 -c:v mjpeg")
+
+	cmd, _, err = encode.SaveSnapshot(context.Background(), "INPUT", "/tmp/100%.jpg")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-update 1", "a % in the path is not a sequence pattern")
+
+	_, _, err = encode.SaveSnapshot(context.Background(), "INPUT", "-")
+	require.ErrorIs(t, err, ErrInvalidOutput)
+
+	_, _, err = encode.SaveSnapshot(nil, "INPUT", "/tmp/snap.jpg") //nolint:staticcheck // a nil context must not panic.
+	require.NoError(t, err)
+
+	encode = Get(&Config{FFMPEG: "/path/that/does/not/exist/ffmpeg"})
+	_, _, err = encode.SaveSnapshot(context.Background(), "INPUT", "/tmp/snap.jpg")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "subcommand failed")
+}
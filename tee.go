@@ -0,0 +1,83 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// TeeVideo saves video from an input to a file and streams the same video as fragmented MP4,
+// using one ffmpeg process and one connection to the camera.
+// Input must be an RTSP URL and output must be a file path. It will be overwritten.
+// Returns command used for diagnostics, io.ReadCloser and error or nil.
+// This will automatically create a context timeout based on the requested capture length.
+// If you want to control the context, use TeeVideoContext().
+func (e *Encoder) TeeVideo(input, output, title string) (string, io.ReadCloser, error) {
+	ctx := context.Background()
+
+	var cancel context.CancelFunc
+
+	if e.config.Time > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.config.Limits.captureTimeout(e.config.Time))
+	}
+
+	cmdStr, stream, err := e.TeeVideoContext(ctx, input, output, title)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+
+		return cmdStr, nil, err
+	}
+
+	if cancel == nil {
+		return cmdStr, stream, nil
+	}
+
+	return cmdStr, &cancelReadCloser{ReadCloser: stream, cancel: cancel}, nil
+}
+
+// TeeVideoContext saves video from an input to a file and streams the same video as fragmented MP4,
+// using one ffmpeg process and one connection to the camera.
+// The stream must be read: ffmpeg stops writing the file while the stream is not consumed.
+// The file is complete once the stream returns io.EOF. Close returns any ffmpeg failure.
+// Use the context to add a timeout value (max run duration) to the ffmpeg command.
+//
+//nolint:contextcheck // caller-provided context is accepted and used for command execution.
+func (e *Encoder) TeeVideoContext(ctx context.Context, input, output, title string) (string, io.ReadCloser, error) {
+	if input == "" {
+		return "", nil, ErrInvalidInput
+	}
+
+	if output == "" || output == "-" {
+		return "", nil, ErrInvalidOutput
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return e.streamVideo(ctx, &videoJob{input: input, output: "-", tee: output, title: title, limit: true})
+}
+
+// teeArgs returns the options and output for the tee muxer: a file and fragmented MP4 on stdout.
+func (e *Encoder) teeArgs(container, file string) []string {
+	var arg []string
+
+	// mov and mp4 need codec headers up front, and tee cannot provide them per output.
+	if !e.config.Copy {
+		arg = append(arg, "-flags:v", "+global_header")
+	}
+
+	options := "f=" + container
+	if e.faststart(container) {
+		options += ":movflags=+faststart"
+	}
+
+	return append(arg, "["+options+"]"+teeEscape(file)+"|[f=mp4:movflags=frag_keyframe+empty_moov]pipe:1")
+}
+
+// teeEscape escapes the characters the tee muxer treats specially in an output name.
+func teeEscape(name string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `|`, `\|`, `[`, `\[`, `]`, `\]`).Replace(name)
+}
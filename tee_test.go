@@ -0,0 +1,38 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeeVideo(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{FFMPEG: "echo"})
+	cmd, stream, err := encode.TeeVideo("rtsp://example.local/stream", "/tmp/out.mov", "")
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	require.Contains(t, string(data), "-f tee")
+	require.Contains(t, cmd, "-rtsp_transport tcp")
+	require.Contains(t, cmd, "-metadata title=out.mov")
+	require.Contains(t, cmd, "-vcodec libx264")
+	require.Contains(t, cmd, "-flags:v +global_header")
+	require.Contains(t, cmd,
+		"'[f=mov:movflags=+faststart]/tmp/out.mov|[f=mp4:movflags=frag_keyframe+empty_moov]pipe:1'")
+
+	encode = Get(&Config{FFMPEG: "echo", Copy: true})
+	args := encode.videoArgs(&videoJob{input: "INPUT", output: "-", tee: "/tmp/a|b.mov"})
+	require.Equal(t, `[f=mov]/tmp/a\|b.mov|[f=mp4:movflags=frag_keyframe+empty_moov]pipe:1`, args[len(args)-1])
+	require.NotContains(t, args, "+global_header")
+
+	_, _, err = encode.TeeVideoContext(context.Background(), "INPUT", "-", "")
+	require.ErrorIs(t, err, ErrInvalidOutput)
+	_, _, err = encode.TeeVideoContext(context.Background(), "", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,100 @@
+package ffmpeg
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Transpose directions. Use one of these for Config.Transpose.
+// Each one swaps the width and height, like a 90 degree rotation.
+const (
+	TransposeClock      = "clock"       // rotate 90 degrees clockwise.
+	TransposeCClock     = "cclock"      // rotate 90 degrees counter-clockwise.
+	TransposeClockFlip  = "clock_flip"  // rotate 90 degrees clockwise and flip vertically.
+	TransposeCClockFlip = "cclock_flip" // rotate 90 degrees counter-clockwise and flip vertically.
+)
+
+// Crop is a region of the source video, in source pixels, measured from the top left.
+// The zero value does not crop.
+type Crop struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// fixTransforms makes sure geometric transforms are sane, and picks an output size
+// with the right shape when neither Width nor Height is configured.
+func (e *Encoder) fixTransforms() {
+	if e.config.Crop.Width <= 0 || e.config.Crop.Height <= 0 || e.config.Crop.X < 0 || e.config.Crop.Y < 0 {
+		e.config.Crop = Crop{}
+	}
+
+	const fullTurn = 360
+
+	e.config.Rotate = (e.config.Rotate%fullTurn + fullTurn) % fullTurn
+	if !slices.Contains([]int{0, 90, 180, 270}, e.config.Rotate) { //nolint:mnd // these are degrees.
+		e.config.Rotate = 0
+	}
+
+	if !slices.Contains([]string{TransposeClock, TransposeCClock, TransposeClockFlip, TransposeCClockFlip},
+		e.config.Transpose) {
+		e.config.Transpose = ""
+	}
+
+	if e.config.Width != 0 || e.config.Height != 0 {
+		return
+	}
+
+	width, height := e.config.Limits.DefaultFrameWidth, e.config.Limits.DefaultFrameHeight
+	if e.config.Crop.Width > 0 {
+		width, height = e.config.Crop.Width, e.config.Crop.Height
+	}
+
+	if e.swapsAxes() {
+		width, height = height, width
+	}
+
+	e.config.Width, e.config.Height = width, height
+}
+
+// swapsAxes returns true if the transforms turn the picture sideways.
+func (e *Encoder) swapsAxes() bool {
+	quarterTurn := e.config.Rotate == 90 || e.config.Rotate == 270 //nolint:mnd // these are degrees.
+
+	return quarterTurn != (e.config.Transpose != "")
+}
+
+// transformFilters returns the filters applied to video before it is scaled, in order.
+// Empty if none are configured.
+func (e *Encoder) transformFilters() []string {
+	var filters []string
+
+	if crop := e.config.Crop; crop.Width > 0 && crop.Height > 0 {
+		filters = append(filters, "crop="+strconv.Itoa(crop.Width)+":"+strconv.Itoa(crop.Height)+
+			":"+strconv.Itoa(crop.X)+":"+strconv.Itoa(crop.Y))
+	}
+
+	if e.config.Transpose != "" {
+		filters = append(filters, "transpose="+e.config.Transpose)
+	}
+
+	switch e.config.Rotate {
+	case 90: //nolint:mnd // these are degrees.
+		filters = append(filters, "transpose="+TransposeClock)
+	case 180: //nolint:mnd // these are degrees.
+		filters = append(filters, "hflip", "vflip")
+	case 270: //nolint:mnd // these are degrees.
+		filters = append(filters, "transpose="+TransposeCClock)
+	}
+
+	if e.config.FlipH {
+		filters = append(filters, "hflip")
+	}
+
+	if e.config.FlipV {
+		filters = append(filters, "vflip")
+	}
+
+	return filters
+}
@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformFilters(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{
+		FFMPEG:  "echo",
+		Crop:    Crop{X: 100, Y: 50, Width: 600, Height: 400},
+		Rotate:  -90,
+		FlipH:   true,
+		Overlay: &Overlay{Text: "cam"},
+	})
+	config := encode.Config()
+	require.Equal(t, 270, config.Rotate)
+	require.Equal(t, 400, config.Width, "the output size follows the crop and rotation")
+	require.Equal(t, 600, config.Height, "the output size follows the crop and rotation")
+
+	cmd, _, err := encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "-vf crop=600:400:100:50,transpose=cclock,hflip,scale=400:600,drawtext=text=cam:")
+
+	encode = Get(&Config{FFMPEG: "echo", Rotate: 45, Transpose: "sideways", Crop: Crop{Width: -1}})
+	config = encode.Config()
+	require.Zero(t, config.Rotate)
+	require.Empty(t, config.Transpose)
+	require.Equal(t, Crop{}, config.Crop)
+	require.Empty(t, encode.transformFilters())
+
+	encode = Get(&Config{Rotate: 90, Transpose: TransposeClockFlip})
+	require.False(t, encode.swapsAxes(), "two quarter turns are a half turn")
+	require.Equal(t, DefaultFrameWidth, encode.Config().Width)
+	require.Equal(t, []string{"transpose=clock_flip", "transpose=clock"}, encode.transformFilters())
+
+	encode = Get(&Config{FFMPEG: "echo", Width: 640, Height: 480, Rotate: 180})
+	require.Equal(t, 640, encode.Config().Width, "a configured size is kept")
+	require.Contains(t, encode.Command("INPUT", "-", ""), "-vf hflip,vflip,scale=640:480 ")
+
+	encode = Get(&Config{FFMPEG: "echo", Copy: true, FlipV: true})
+	_, _, err = encode.SaveVideo("INPUT", "/tmp/out.mov", "")
+	require.ErrorIs(t, err, ErrCopyFilter)
+}
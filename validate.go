@@ -0,0 +1,260 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate returns every value in the config that Get() and the Set* methods would silently replace,
+// like a width beyond Limits.MaximumFrameSize, a level the codec does not have, or an unknown scale mode.
+// Odd frame sizes, and Limits that are negative or out of order, are reported too.
+// Zero values are not errors; they mean "use the default." Each problem wraps ErrInvalidValue,
+// and they are joined with errors.Join. Returns nil if the config is valid.
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	limits := c.Limits.withDefaults()
+	codec := limits.codec(c.Codec)
+
+	errs := []error{
+		checkChoice("codec", c.Codec, knownCodecs()),
+		checkChoice("profile", c.Prof, codec.Profiles),
+		checkChoice("level", c.Level, codec.Levels),
+		checkChoice("audio codec", c.AudioCodec, []string{AudioCopy, AudioAuto, AudioAAC, AudioOpus, AudioMP3}),
+		checkChoice("scale", c.Scale, []string{ScaleStretch, ScaleFit, ScaleFill, ScaleFitWidth, ScaleFitHeight}),
+		checkChoice("transpose", c.Transpose,
+			[]string{TransposeClock, TransposeCClock, TransposeClockFlip, TransposeCClockFlip}),
+		checkRange("width", c.Width, limits.MinimumFrameSize, limits.MaximumFrameSize),
+		checkRange("height", c.Height, limits.MinimumFrameSize, limits.MaximumFrameSize),
+		checkEven("width", c.Width),
+		checkEven("height", c.Height),
+		checkRange("crf", c.CRF, codec.MinimumCRF, codec.MaximumCRF),
+		checkRange("rate", c.Rate, limits.MinimumFrameRate, limits.MaximumFrameRate),
+		checkRange("time", c.Time, 0, limits.MaximumCaptureTime),
+		checkRange("size", c.Size, 0, limits.MaximumCaptureSize),
+		checkPositive("audio bitrate", c.AudioBitrate),
+		checkPositive("audio rate", c.AudioRate),
+		checkPositive("audio channels", c.AudioChannels),
+	}
+
+	errs = append(errs, limits.check()...)
+
+	const quarterTurn = 90
+	if c.Rotate%quarterTurn != 0 {
+		errs = append(errs, fmt.Errorf("%w: rotate %d is not a multiple of 90", ErrInvalidValue, c.Rotate))
+	}
+
+	if c.Crop != (Crop{}) && (c.Crop.Width <= 0 || c.Crop.Height <= 0 || c.Crop.X < 0 || c.Crop.Y < 0) {
+		errs = append(errs, fmt.Errorf("%w: crop %dx%d at %d,%d is not a positive size at a non-negative offset",
+			ErrInvalidValue, c.Crop.Width, c.Crop.Height, c.Crop.X, c.Crop.Y))
+	}
+
+	if c.Overlay != nil {
+		errs = append(errs, checkChoice("overlay position", c.Overlay.Position,
+			[]string{TopLeft, TopRight, BottomLeft, BottomRight}))
+	}
+
+	// Masks are checked as Get() sets them up, against the Crop size when there is one.
+	if err := Get(c).checkFilters(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidValue, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetAudioStrict is SetAudio, but returns an error instead of turning audio off if the value is not a boolean.
+func (e *Encoder) SetAudioStrict(audio string) (bool, error) {
+	if _, err := strconv.ParseBool(audio); err != nil && audio != "" {
+		return e.config.Audio, fmt.Errorf("%w: audio %q is not true or false", ErrInvalidValue, audio)
+	}
+
+	return e.SetAudio(audio), nil
+}
+
+// SetCodecStrict is SetCodec, but returns an error instead of using DefaultCodec if the codec is unknown.
+func (e *Encoder) SetCodecStrict(codec string) (string, error) {
+	if err := checkChoice("codec", codec, knownCodecs()); err != nil {
+		return e.config.Codec, err
+	}
+
+	return e.SetCodec(codec), nil
+}
+
+// SetProfileStrict is SetProfile, but returns an error instead of using the
+// codec's default profile if the codec does not have the profile.
+func (e *Encoder) SetProfileStrict(profile string) (string, error) {
+	if err := checkChoice("profile", profile, e.codec().Profiles); err != nil {
+		return e.config.Prof, err
+	}
+
+	return e.SetProfile(profile), nil
+}
+
+// SetLevelStrict is SetLevel, but returns an error instead of using the
+// codec's default level if the codec does not have the level.
+func (e *Encoder) SetLevelStrict(level string) (string, error) {
+	if err := checkChoice("level", level, e.codec().Levels); err != nil {
+		return e.config.Level, err
+	}
+
+	return e.SetLevel(level), nil
+}
+
+// SetAudioCodecStrict is SetAudioCodec, but returns an error instead of using copy if the codec is unknown.
+func (e *Encoder) SetAudioCodecStrict(codec string) (string, error) {
+	err := checkChoice("audio codec", codec, []string{AudioCopy, AudioAuto, AudioAAC, AudioOpus, AudioMP3})
+	if err != nil {
+		return e.config.AudioCodec, err
+	}
+
+	return e.SetAudioCodec(codec), nil
+}
+
+// SetScaleStrict is SetScale, but returns an error instead of using stretch if the mode is unknown.
+func (e *Encoder) SetScaleStrict(mode string) (string, error) {
+	err := checkChoice("scale", mode, []string{ScaleStretch, ScaleFit, ScaleFill, ScaleFitWidth, ScaleFitHeight})
+	if err != nil {
+		return e.config.Scale, err
+	}
+
+	return e.SetScale(mode), nil
+}
+
+// SetWidthStrict is SetWidth, but returns an error instead of using a default or limit
+// if the value is not a number, is odd, or is outside the limits' MinimumFrameSize and MaximumFrameSize.
+func (e *Encoder) SetWidthStrict(width string) (int, error) {
+	limits := &e.config.Limits
+	if err := parseRange("width", width, limits.MinimumFrameSize, limits.MaximumFrameSize); err != nil {
+		return e.config.Width, err
+	}
+
+	number, _ := strconv.Atoi(width) // parseRange checked it.
+	if err := checkEven("width", number); err != nil {
+		return e.config.Width, err
+	}
+
+	return e.SetWidth(width), nil
+}
+
+// SetHeightStrict is SetHeight, but returns an error instead of using a default or limit
+// if the value is not a number, is odd, or is outside the limits' MinimumFrameSize and MaximumFrameSize.
+func (e *Encoder) SetHeightStrict(height string) (int, error) {
+	limits := &e.config.Limits
+	if err := parseRange("height", height, limits.MinimumFrameSize, limits.MaximumFrameSize); err != nil {
+		return e.config.Height, err
+	}
+
+	number, _ := strconv.Atoi(height) // parseRange checked it.
+	if err := checkEven("height", number); err != nil {
+		return e.config.Height, err
+	}
+
+	return e.SetHeight(height), nil
+}
+
+// SetCRFStrict is SetCRF, but returns an error instead of using a default or limit
+// if the value is not a number, or is outside the codec's CRF range.
+func (e *Encoder) SetCRFStrict(crf string) (int, error) {
+	codec := e.codec()
+	if err := parseRange("crf", crf, codec.MinimumCRF, codec.MaximumCRF); err != nil {
+		return e.config.CRF, err
+	}
+
+	return e.SetCRF(crf), nil
+}
+
+// SetTimeStrict is SetTime, but returns an error instead of using a default or limit
+// if the value is not a number, is negative, or is more than the limits' MaximumCaptureTime.
+func (e *Encoder) SetTimeStrict(seconds string) (int, error) {
+	if err := parseRange("time", seconds, 0, e.config.Limits.MaximumCaptureTime); err != nil {
+		return e.config.Time, err
+	}
+
+	return e.SetTime(seconds), nil
+}
+
+// SetRateStrict is SetRate, but returns an error instead of using a default or limit
+// if the value is not a number, or is outside the limits' MinimumFrameRate and MaximumFrameRate.
+func (e *Encoder) SetRateStrict(rate string) (int, error) {
+	limits := &e.config.Limits
+	if err := parseRange("rate", rate, limits.MinimumFrameRate, limits.MaximumFrameRate); err != nil {
+		return e.config.Rate, err
+	}
+
+	return e.SetRate(rate), nil
+}
+
+// SetSizeStrict is SetSize, but returns an error instead of using a default or limit
+// if the value is not a number, is negative, or is more than the limits' MaximumCaptureSize.
+func (e *Encoder) SetSizeStrict(size string) (int64, error) {
+	if err := parseRange("size", size, 0, e.config.Limits.MaximumCaptureSize); err != nil {
+		return e.config.Size, err
+	}
+
+	return e.SetSize(size), nil
+}
+
+// knownCodecs returns the names of the video encoders LookupCodec knows.
+func knownCodecs() []string {
+	return []string{CodecH264, CodecH265, CodecVP9, CodecAV1, CodecSVTAV1}
+}
+
+// checkChoice returns an error if value is not empty (the default) and not one of choices.
+func checkChoice(name, value string, choices []string) error {
+	if value == "" || slices.Contains(choices, value) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %q is not one of: %s", ErrInvalidValue, name, value, strings.Join(choices, ", "))
+}
+
+// checkRange returns an error if value is not zero (the default) and not within minimum and maximum.
+func checkRange[T int | int64](name string, value, minimum, maximum T) error {
+	if value == 0 || (value >= minimum && value <= maximum) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %d is outside %d-%d", ErrInvalidValue, name, value, minimum, maximum)
+}
+
+// checkEven returns an error if a frame size is odd; yuv420p needs even frame sizes.
+func checkEven(name string, value int) error {
+	if value%2 == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %d is odd; frame sizes must be even", ErrInvalidValue, name, value)
+}
+
+// checkPositive returns an error if value is negative.
+func checkPositive(name string, value int) error {
+	if value >= 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %d is negative", ErrInvalidValue, name, value)
+}
+
+// parseRange returns an error if value is not empty (the default) and not an integer within minimum and maximum.
+func parseRange[T int | int64](name, value string, minimum, maximum T) error {
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.ParseInt(value, base10, bits64)
+	if err != nil {
+		return fmt.Errorf("%w: %s %q is not a whole number", ErrInvalidValue, name, value)
+	}
+
+	// Compare as int64, so values that overflow int are out of range rather than wrapped.
+	if number != 0 && (number < int64(minimum) || number > int64(maximum)) {
+		return fmt.Errorf("%w: %s %d is outside %d-%d", ErrInvalidValue, name, number, minimum, maximum)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (*Config)(nil).Validate())
+	require.NoError(t, (&Config{}).Validate(), "zero values are defaults")
+	require.NoError(t, (&Config{
+		Codec: CodecH265, Prof: "main10", Width: 1920, Height: 1080, CRF: 28, Rate: 10,
+		Time: 60, Size: 5000000, Scale: ScaleFit, Rotate: -90, AudioCodec: AudioAAC,
+	}).Validate())
+
+	err := (&Config{
+		Codec:         "h264",
+		Level:         "9.9",
+		Width:         9000,
+		Height:        50,
+		CRF:           99,
+		Rate:          -1,
+		Time:          MaximumCaptureTime + 1,
+		Scale:         "zoom",
+		Rotate:        45,
+		AudioChannels: -2,
+		Crop:          Crop{Width: 100},
+		Overlay:       &Overlay{Position: "middle"},
+	}).Validate()
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	var joined interface{ Unwrap() []error }
+	require.ErrorAs(t, err, &joined)
+	require.Len(t, joined.Unwrap(), 12, "every problem is reported: %v", err)
+
+	for _, field := range []string{
+		`codec "h264"`, `level "9.9"`, "width 9000", "height 50", "crf 99", "rate -1", "time 1201",
+		`scale "zoom"`, "rotate 45", "audio channels -2", "crop 100x0", `overlay position "middle"`,
+	} {
+		require.ErrorContains(t, err, field)
+	}
+}
+
+func TestValidateEvenAndLimits(t *testing.T) {
+	t.Parallel()
+
+	require.ErrorIs(t, (&Config{Width: 641}).Validate(), ErrInvalidValue)
+	require.ErrorContains(t, (&Config{Height: 481}).Validate(), "height 481 is odd")
+	require.NoError(t, (&Config{Limits: Limits{MaximumFrameRate: 30, MaximumCaptureTime: 60}}).Validate())
+
+	err := (&Config{Limits: Limits{
+		MinimumFrameRate:   20,  // above the default frame rate.
+		MaximumFrameSize:   640, // below the default frame width and height.
+		DefaultCaptureSize: -1,
+		DefaultSegmentTime: -time.Second,
+	}}).Validate()
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	for _, problem := range []string{
+		"frame rate limits are out of order", "frame height limits are out of order",
+		"frame width limits are out of order", "capture size limits", "segment time limit",
+	} {
+		require.ErrorContains(t, err, problem)
+	}
+}
+
+func TestValidateFilters(t *testing.T) {
+	t.Parallel()
+
+	err := (&Config{Copy: true, Masks: []Mask{{Width: 10, Height: 10}}}).Validate()
+	require.ErrorIs(t, err, ErrInvalidValue)
+	require.ErrorIs(t, err, ErrCopyFilter)
+
+	err = (&Config{Crop: Crop{Width: 640, Height: 480}, Masks: []Mask{{X: 600, Width: 100, Height: 100}}}).Validate()
+	require.ErrorIs(t, err, ErrInvalidMask)
+}
+
+func TestStrictSetters(t *testing.T) {
+	t.Parallel()
+
+	encode := Get(&Config{})
+
+	width, err := encode.SetWidthStrict("640")
+	require.NoError(t, err)
+	require.Equal(t, 640, width)
+
+	width, err = encode.SetWidthStrict("641")
+	require.ErrorIs(t, err, ErrInvalidValue, "odd frame sizes are not rounded")
+	require.Equal(t, 640, width)
+
+	_, err = encode.SetHeightStrict("481")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	width, err = encode.SetWidthStrict("abc")
+	require.ErrorIs(t, err, ErrInvalidValue)
+	require.Equal(t, 640, width, "invalid values do not change the config")
+	require.Equal(t, DefaultFrameWidth, encode.SetWidth("abc"), "the lenient setter still uses the default")
+
+	_, err = encode.SetHeightStrict("9000")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = encode.SetSizeStrict("99999999999999999999")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = encode.SetTimeStrict("-5")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	rate, err := encode.SetRateStrict("")
+	require.NoError(t, err, "empty values are defaults")
+	require.Equal(t, DefaultFrameRate, rate)
+
+	audio, err := encode.SetAudioStrict("yes")
+	require.ErrorIs(t, err, ErrInvalidValue)
+	require.False(t, audio)
+
+	audio, err = encode.SetAudioStrict("true")
+	require.NoError(t, err)
+	require.True(t, audio)
+
+	_, err = encode.SetLevelStrict("3.0")
+	require.NoError(t, err)
+
+	codec, err := encode.SetCodecStrict("vp9")
+	require.ErrorIs(t, err, ErrInvalidValue)
+	require.Equal(t, DefaultCodec, codec)
+
+	_, err = encode.SetCodecStrict(CodecVP9)
+	require.NoError(t, err)
+
+	_, err = encode.SetProfileStrict("high")
+	require.ErrorIs(t, err, ErrInvalidValue, "profiles depend on the codec")
+
+	_, err = encode.SetCRFStrict("55")
+	require.ErrorIs(t, err, ErrInvalidValue, "the CRF range depends on the codec")
+
+	_, err = encode.SetScaleStrict("zoom")
+	require.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = encode.SetAudioCodecStrict("flac")
+	require.ErrorIs(t, err, ErrInvalidValue)
+}